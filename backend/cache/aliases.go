@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"database/sql"
+	"strings"
+)
+
+const SourceMusicBrainz = "musicbrainz"
+
+type Alias struct {
+	Alias  string
+	Source string
+}
+
+func (c *LyricsCache) Aliases(artist string) ([]Alias, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rows, err := c.db.Query(
+		`SELECT alias, source FROM artist_aliases WHERE artist = ?
+		 ORDER BY source = ? DESC, priority, rowid`,
+		normalize(artist), SourceManual,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []Alias
+	for rows.Next() {
+		var a Alias
+		if err := rows.Scan(&a.Alias, &a.Source); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+func (c *LyricsCache) AliasesSeeded(artist string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var n int
+	c.db.QueryRow("SELECT COUNT(*) FROM alias_seeds WHERE artist = ?", normalize(artist)).Scan(&n)
+	return n > 0
+}
+
+func (c *LyricsCache) SeedAliases(artist string, aliases []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.withTx(func(tx *sql.Tx) error {
+		for i, alias := range aliases {
+			_, err := tx.Exec(
+				`INSERT OR IGNORE INTO artist_aliases (artist, alias, source, priority)
+				 VALUES (?, ?, ?, ?)`,
+				normalize(artist), strings.TrimSpace(alias), SourceMusicBrainz, i,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("INSERT OR REPLACE INTO alias_seeds (artist) VALUES (?)", normalize(artist))
+		return err
+	})
+}
+
+func (c *LyricsCache) AddAlias(artist, alias string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.db.Exec(
+		`INSERT INTO artist_aliases (artist, alias, source) VALUES (?, ?, ?)
+		 ON CONFLICT (artist, alias) DO UPDATE SET source = excluded.source, priority = 0`,
+		normalize(artist), strings.TrimSpace(alias), SourceManual,
+	)
+	return err
+}
+
+func (c *LyricsCache) RemoveAlias(artist, alias string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res, err := c.db.Exec("DELETE FROM artist_aliases WHERE artist = ? AND alias = ?",
+		normalize(artist), strings.TrimSpace(alias))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
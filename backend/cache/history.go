@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrNotFound = errors.New("entry not found")
+
+type HistoryEntry struct {
+	ID        int64
+	Action    string
+	ChangedAt string
+	Entry
+}
+
+func (c *LyricsCache) SetManual(artist, title string, e Entry, action string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.Source = SourceManual
+	e.Confidence = 1
+
+	return c.withTx(func(tx *sql.Tx) error {
+		if err := snapshot(tx, artist, title, action); err != nil {
+			return err
+		}
+		return upsert(tx, artist, title, e, true)
+	})
+}
+
+func (c *LyricsCache) Delete(artist, title string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.withTx(func(tx *sql.Tx) error {
+		if err := snapshot(tx, artist, title, "delete"); err != nil {
+			return err
+		}
+		res, err := tx.Exec("DELETE FROM lyrics WHERE artist = ? AND title = ?",
+			normalize(artist), normalize(title))
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (c *LyricsCache) History(artist, title string) ([]HistoryEntry, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rows, err := c.db.Query(
+		`SELECT id, action, changed_at, lyrics, synced, duration, source, confidence, found, instrumental
+		 FROM lyrics_history WHERE artist = ? AND title = ? ORDER BY id DESC`,
+		normalize(artist), normalize(title),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryEntry
+	for rows.Next() {
+		h, err := scanHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *h)
+	}
+	return history, rows.Err()
+}
+
+func (c *LyricsCache) Revert(artist, title string, id int64) (*Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reverted *Entry
+	err := c.withTx(func(tx *sql.Tx) error {
+		h, err := scanHistory(tx.QueryRow(
+			`SELECT id, action, changed_at, lyrics, synced, duration, source, confidence, found, instrumental
+			 FROM lyrics_history WHERE id = ? AND artist = ? AND title = ?`,
+			id, normalize(artist), normalize(title),
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := snapshot(tx, artist, title, "revert"); err != nil {
+			return err
+		}
+		if err := upsert(tx, artist, title, h.Entry, true); err != nil {
+			return err
+		}
+		reverted = &h.Entry
+		return nil
+	})
+	return reverted, err
+}
+
+func (c *LyricsCache) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func snapshot(tx *sql.Tx, artist, title, action string) error {
+	_, err := tx.Exec(
+		`INSERT INTO lyrics_history
+		 (artist, title, lyrics, synced, duration, source, confidence, found, instrumental, action)
+		 SELECT artist, title, lyrics, synced, duration, source, confidence, found, instrumental, ?
+		 FROM lyrics WHERE artist = ? AND title = ?`,
+		action, normalize(artist), normalize(title),
+	)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanHistory(row rowScanner) (*HistoryEntry, error) {
+	var h HistoryEntry
+	var changedAt, lyrics, synced, source sql.NullString
+	var duration, confidence sql.NullFloat64
+	var found, instrumental int
+
+	err := row.Scan(&h.ID, &h.Action, &changedAt, &lyrics, &synced, &duration,
+		&source, &confidence, &found, &instrumental)
+	if err != nil {
+		return nil, err
+	}
+
+	h.ChangedAt = changedAt.String
+	h.Entry = Entry{
+		Lyrics:       lyrics.String,
+		Synced:       synced.String,
+		Duration:     duration.Float64,
+		Source:       source.String,
+		Confidence:   confidence.Float64,
+		Found:        found == 1,
+		Instrumental: instrumental == 1,
+	}
+	return &h, nil
+}
@@ -6,22 +6,30 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type LyricsCache struct {
-	db *sql.DB
-	mu sync.RWMutex
+	db      *sql.DB
+	mu      sync.RWMutex
+	missTTL time.Duration
 }
 
+const SourceManual = "manual"
+
 type Entry struct {
-	Lyrics string
-	Source string
-	Found  bool
+	Lyrics       string
+	Synced       string
+	Duration     float64
+	Source       string
+	Confidence   float64
+	Found        bool
+	Instrumental bool
 }
 
-func New(dbPath string) (*LyricsCache, error) {
+func New(dbPath string, missTTL time.Duration) (*LyricsCache, error) {
 
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -48,8 +56,88 @@ func New(dbPath string) (*LyricsCache, error) {
 		return nil, err
 	}
 
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS lyrics_history (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			artist       TEXT NOT NULL,
+			title        TEXT NOT NULL,
+			lyrics       TEXT,
+			synced       TEXT,
+			duration     REAL,
+			source       TEXT,
+			confidence   REAL,
+			found        INTEGER NOT NULL DEFAULT 0,
+			instrumental INTEGER NOT NULL DEFAULT 0,
+			action       TEXT NOT NULL,
+			changed_at   DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS lyrics_history_key ON lyrics_history (artist, title)
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS artist_aliases (
+			artist     TEXT NOT NULL,
+			alias      TEXT NOT NULL COLLATE NOCASE,
+			source     TEXT NOT NULL,
+			priority   INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (artist, alias)
+		);
+		CREATE TABLE IF NOT EXISTS alias_seeds (
+			artist    TEXT PRIMARY KEY,
+			seeded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Println("[cache] SQLite initialized at", dbPath)
-	return &LyricsCache{db: db}, nil
+	return &LyricsCache{db: db, missTTL: missTTL}, nil
+}
+
+func migrate(db *sql.DB) error {
+	columns := map[string]string{
+		"synced":       "TEXT",
+		"duration":     "REAL",
+		"confidence":   "REAL",
+		"instrumental": "INTEGER NOT NULL DEFAULT 0",
+	}
+
+	rows, err := db.Query("PRAGMA table_info(lyrics)")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, kind string
+		var def sql.NullString
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &def, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	for name, kind := range columns {
+		if existing[name] {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE lyrics ADD COLUMN " + name + " " + kind); err != nil {
+			return err
+		}
+		log.Printf("[cache] added column %s", name)
+	}
+	return nil
 }
 
 func (c *LyricsCache) Get(artist, title string) (*Entry, bool) {
@@ -57,43 +145,133 @@ func (c *LyricsCache) Get(artist, title string) (*Entry, bool) {
 	defer c.mu.RUnlock()
 
 	var lyrics sql.NullString
+	var synced sql.NullString
+	var duration sql.NullFloat64
 	var source sql.NullString
-	var found int
+	var confidence sql.NullFloat64
+	var found, instrumental int
+	var age sql.NullFloat64
 
 	err := c.db.QueryRow(
-		"SELECT lyrics, source, found FROM lyrics WHERE artist = ? AND title = ?",
+		`SELECT lyrics, synced, duration, source, confidence, found, instrumental,
+		        (julianday('now') - julianday(created_at)) * 86400
+		 FROM lyrics WHERE artist = ? AND title = ?`,
 		normalize(artist), normalize(title),
-	).Scan(&lyrics, &source, &found)
+	).Scan(&lyrics, &synced, &duration, &source, &confidence, &found, &instrumental, &age)
 
 	if err != nil {
 		return nil, false
 	}
 
+	if found == 0 && instrumental == 0 && c.missTTL > 0 && age.Float64 > c.missTTL.Seconds() {
+		return nil, false
+	}
+
 	return &Entry{
-		Lyrics: lyrics.String,
-		Source: source.String,
-		Found:  found == 1,
+		Lyrics:       lyrics.String,
+		Synced:       synced.String,
+		Duration:     duration.Float64,
+		Source:       source.String,
+		Confidence:   confidence.Float64,
+		Found:        found == 1,
+		Instrumental: instrumental == 1,
 	}, true
 }
 
-func (c *LyricsCache) Set(artist, title, lyrics, source string, found bool) {
+func (c *LyricsCache) Set(artist, title string, e Entry) {
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	foundInt := 0
-	if found {
-		foundInt = 1
+	if err := upsert(c.db, artist, title, e, false); err != nil {
+		log.Printf("[cache] write error: %v", err)
 	}
+}
 
-	_, err := c.db.Exec(
-		`INSERT OR REPLACE INTO lyrics (artist, title, lyrics, source, found)
-		 VALUES (?, ?, ?, ?, ?)`,
-		normalize(artist), normalize(title), lyrics, source, foundInt,
-	)
+type Key struct {
+	Artist string
+	Title  string
+}
+
+func (c *LyricsCache) TitleKeys() ([]Key, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rows, err := c.db.Query("SELECT artist, title FROM lyrics")
 	if err != nil {
-		log.Printf("[cache] write error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []Key
+	for rows.Next() {
+		var k Key
+		if err := rows.Scan(&k.Artist, &k.Title); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Rekey moves an entry and its history from one title key to another. It
+// does nothing when the old key has no entry or the new one is taken.
+func (c *LyricsCache) Rekey(artist, from, to string) (bool, error) {
+	if normalize(from) == normalize(to) {
+		return false, nil
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	a, f, t := normalize(artist), normalize(from), normalize(to)
+	moved := false
+	err := c.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			`UPDATE lyrics SET title = ? WHERE artist = ? AND title = ?
+			 AND NOT EXISTS (SELECT 1 FROM lyrics WHERE artist = ? AND title = ?)`,
+			t, a, f, a, t)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		moved = true
+		_, err = tx.Exec("UPDATE lyrics_history SET title = ? WHERE artist = ? AND title = ?", t, a, f)
+		return err
+	})
+	return moved, err
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func upsert(db execer, artist, title string, e Entry, force bool) error {
+	query := `INSERT INTO lyrics
+		 (artist, title, lyrics, synced, duration, source, confidence, found, instrumental)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (artist, title) DO UPDATE SET
+		   lyrics = excluded.lyrics, synced = excluded.synced, duration = excluded.duration,
+		   source = excluded.source, confidence = excluded.confidence, found = excluded.found,
+		   instrumental = excluded.instrumental, created_at = CURRENT_TIMESTAMP`
+	if !force {
+		query += ` WHERE lyrics.source IS NOT '` + SourceManual + `'`
+	}
+
+	_, err := db.Exec(query,
+		normalize(artist), normalize(title),
+		e.Lyrics, e.Synced, e.Duration, e.Source, e.Confidence, boolInt(e.Found), boolInt(e.Instrumental),
+	)
+	return err
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
 }
 
 func (c *LyricsCache) Stats() (total int, found int) {
@@ -105,6 +283,27 @@ func (c *LyricsCache) Stats() (total int, found int) {
 	return
 }
 
+func (c *LyricsCache) AllLyrics() ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rows, err := c.db.Query("SELECT lyrics FROM lyrics WHERE found = 1 AND lyrics IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []string
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			return nil, err
+		}
+		all = append(all, text)
+	}
+	return all, rows.Err()
+}
+
 func (c *LyricsCache) Close() error {
 	return c.db.Close()
 }
@@ -2,8 +2,11 @@ package config
 
 import (
 	"bufio"
+	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +15,18 @@ type Config struct {
 	GeniusToken  string
 	AllowOrigins string
 	DBPath       string
+	AdminToken   string
+	LyricsDir    string
+	MusicDir     string
+	Offline      bool
+
+	MissTTL         time.Duration
+	RecheckInterval time.Duration
+	RecheckLimit    int
+
+	HedgeDelay        time.Duration
+	LrclibConcurrency int
+	GeniusConcurrency int
 }
 
 func Load() *Config {
@@ -23,6 +38,18 @@ func Load() *Config {
 		GeniusToken:  getEnv("GENIUS_TOKEN", ""),
 		AllowOrigins: getEnv("ALLOW_ORIGINS", "*"),
 		DBPath:       getEnv("DB_PATH", "./data/lyrics_cache.db"),
+		AdminToken:   getEnv("ADMIN_TOKEN", ""),
+		LyricsDir:    getEnv("LYRICS_DIR", ""),
+		MusicDir:     getEnv("MUSIC_DIR", ""),
+		Offline:      getEnv("OFFLINE", "false") == "true",
+
+		MissTTL:         getDuration("MISS_TTL", 30*24*time.Hour),
+		RecheckInterval: getDuration("RECHECK_INTERVAL", 6*time.Hour),
+		RecheckLimit:    getInt("RECHECK_LIMIT", 50),
+
+		HedgeDelay:        getDuration("HEDGE_DELAY", 0),
+		LrclibConcurrency: getInt("LRCLIB_CONCURRENCY", 8),
+		GeniusConcurrency: getInt("GENIUS_CONCURRENCY", 2),
 	}
 }
 
@@ -33,6 +60,32 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("[config] bad %s=%q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+func getInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("[config] bad %s=%q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
 func loadEnvFile(path string) {
 	file, err := os.Open(path)
 	if err != nil {
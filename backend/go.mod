@@ -3,6 +3,6 @@ module lastfm-lyrics
 go 1.25.0
 
 require (
-	github.com/mattn/go-sqlite3 v1.14.34 // indirect
-	golang.org/x/net v0.50.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.34
+	golang.org/x/net v0.50.0
 )
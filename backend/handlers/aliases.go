@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"lastfm-lyrics/cache"
+	"lastfm-lyrics/models"
+)
+
+func (h *Handler) Aliases(w http.ResponseWriter, r *http.Request) {
+	artist := strings.TrimSpace(r.URL.Query().Get("artist"))
+	alias := strings.TrimSpace(r.URL.Query().Get("alias"))
+	if artist == "" {
+		writeJSON(w, 400, map[string]string{"error": "artist is required"})
+		return
+	}
+	if r.Method != http.MethodGet && alias == "" {
+		writeJSON(w, 400, map[string]string{"error": "alias is required"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if err := h.cache.AddAlias(artist, alias); err != nil {
+			writeJSON(w, 500, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("[aliases] added %s → %s", artist, alias)
+
+	case http.MethodDelete:
+		err := h.cache.RemoveAlias(artist, alias)
+		if errors.Is(err, cache.ErrNotFound) {
+			writeJSON(w, 404, map[string]string{"error": "alias not found"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, 500, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("[aliases] removed %s → %s", artist, alias)
+
+	default:
+		writeJSON(w, 405, map[string]string{"error": "GET, PUT or DELETE only"})
+		return
+	}
+
+	aliases, err := h.cache.Aliases(artist)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	resp := models.ArtistAliases{Artist: artist, Aliases: []models.ArtistAlias{}}
+	for _, a := range aliases {
+		resp.Aliases = append(resp.Aliases, models.ArtistAlias{Alias: a.Alias, Source: a.Source})
+	}
+	writeJSON(w, 200, resp)
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"lastfm-lyrics/cache"
 	"lastfm-lyrics/config"
@@ -16,17 +19,36 @@ import (
 )
 
 type Handler struct {
-	cfg     *config.Config
-	cache   *cache.LyricsCache
-	tasks   map[string]*models.TaskStatus
-	tasksMu sync.RWMutex
+	cfg      *config.Config
+	cache    *cache.LyricsCache
+	lyrics   *services.Lyrics
+	library  *services.Library
+	analyzer *services.Analyzer
+	tasks    map[string]*models.TaskStatus
+	words    map[string][]models.WordCount
+	cancels  map[string]context.CancelFunc
+	tasksMu  sync.RWMutex
 }
 
-func New(cfg *config.Config, c *cache.LyricsCache) *Handler {
+func New(cfg *config.Config, c *cache.LyricsCache, analyzer *services.Analyzer) *Handler {
+	local := services.NewLocalLyrics(cfg.LyricsDir)
+	library := services.NewLibrary(cfg.MusicDir)
+
 	return &Handler{
 		cfg:   cfg,
 		cache: c,
-		tasks: make(map[string]*models.TaskStatus),
+		lyrics: services.NewLyrics(services.LyricsOptions{
+			GeniusToken:   cfg.GeniusToken,
+			Offline:       cfg.Offline,
+			HedgeDelay:    cfg.HedgeDelay,
+			LrclibWorkers: cfg.LrclibConcurrency,
+			GeniusWorkers: cfg.GeniusConcurrency,
+		}, local, library, c),
+		library:  library,
+		analyzer: analyzer,
+		tasks:    make(map[string]*models.TaskStatus),
+		words:    make(map[string][]models.WordCount),
+		cancels:  make(map[string]context.CancelFunc),
 	}
 }
 
@@ -50,33 +72,12 @@ func (h *Handler) Analyze(w http.ResponseWriter, r *http.Request) {
 	if req.MaxTracks == 0 {
 		req.MaxTracks = 500
 	}
-	req.ExcludeStopWords = true
 
 	taskID := fmt.Sprintf("%x", md5.Sum(
-		[]byte(req.Username+"_"+req.From+"_"+req.To),
+		[]byte(req.Username+"_"+req.From+"_"+req.To+filtersKey(req.AnalysisOptions)),
 	))
 
-	h.tasksMu.RLock()
-	existing, exists := h.tasks[taskID]
-	h.tasksMu.RUnlock()
-
-	if exists {
-		switch existing.Phase {
-		case "tracks", "lyrics", "analyzing":
-			writeJSON(w, 200, map[string]string{
-				"task_id": taskID,
-				"status":  "already_running",
-			})
-			return
-		}
-	}
-
-	h.tasksMu.Lock()
-	h.tasks[taskID] = &models.TaskStatus{ID: taskID, Phase: "pending"}
-	h.tasksMu.Unlock()
-
-	go h.runAnalysis(taskID, req)
-	writeJSON(w, 200, map[string]string{"task_id": taskID})
+	h.startTask(w, taskID, func(ctx context.Context) { h.runAnalysis(ctx, taskID, req) })
 }
 
 func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
@@ -88,8 +89,25 @@ func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	topN := min(queryInt(r.URL.Query().Get("top_n"), defaultTopN), maxTopN)
+	if topN <= 0 {
+		topN = defaultTopN
+	}
+
+	// Tasks are shared by everyone posting the same filters, so top_n is
+	// applied to a copy when serving rather than stored on the task.
 	h.tasksMu.RLock()
-	status, exists := h.tasks[taskID]
+	stored, exists := h.tasks[taskID]
+	var status models.TaskStatus
+	if exists {
+		status = *stored
+		if words := h.words[taskID]; status.Result != nil && words != nil {
+			result := *status.Result
+			result.Words = words[:min(topN, len(words))]
+			result.Filters.TopN = topN
+			status.Result = &result
+		}
+	}
 	h.tasksMu.RUnlock()
 
 	if !exists {
@@ -100,6 +118,22 @@ func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, status)
 }
 
+func (h *Handler) Normalize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, 405, map[string]string{"error": "GET only"})
+		return
+	}
+
+	artist := r.URL.Query().Get("artist")
+	title := r.URL.Query().Get("title")
+	if artist == "" && title == "" {
+		writeJSON(w, 400, map[string]string{"error": "artist or title is required"})
+		return
+	}
+
+	writeJSON(w, 200, services.ExplainNormalization(artist, title))
+}
+
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	total, found := h.cache.Stats()
 	writeJSON(w, 200, map[string]interface{}{
@@ -109,84 +143,24 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (h *Handler) runAnalysis(taskID string, req models.AnalysisRequest) {
-	update := func(fn func(*models.TaskStatus)) {
-		h.tasksMu.Lock()
-		if s, ok := h.tasks[taskID]; ok {
-			fn(s)
-		}
-		h.tasksMu.Unlock()
-	}
-
-	setError := func(msg string) {
-		update(func(s *models.TaskStatus) {
-			s.Phase = "error"
-			s.Error = msg
-		})
-	}
-
-	update(func(s *models.TaskStatus) { s.Phase = "tracks" })
+func (h *Handler) runAnalysis(ctx context.Context, taskID string, req models.AnalysisRequest) {
+	h.updateTask(ctx, taskID, func(s *models.TaskStatus) { s.Phase = "tracks" })
 	log.Printf("[task:%s] fetching tracks for %s (%s to %s)",
 		taskID, req.Username, req.From, req.To)
 
 	lastfm := services.NewLastFM(h.cfg.LastFMKey)
-	tracks, totalScrobbles, err := lastfm.GetTracks(req.Username, req.From, req.To, req.MaxTracks)
+	tracks, totalScrobbles, err := lastfm.GetTracks(ctx, req.Username, req.From, req.To, req.MaxTracks)
 	if err != nil {
-		setError(err.Error())
+		h.failTask(ctx, taskID, err.Error())
 		return
 	}
 
 	if len(tracks) == 0 {
-		setError("No tracks found for this period")
-		return
-	}
-
-	update(func(s *models.TaskStatus) {
-		s.TotalTracks = len(tracks)
-	})
-
-	update(func(s *models.TaskStatus) { s.Phase = "lyrics" })
-	log.Printf("[task:%s] searching lyrics for %d tracks", taskID, len(tracks))
-
-	lyricsSvc := services.NewLyrics(h.cfg.GeniusToken, h.cache)
-
-	lyricsMap := lyricsSvc.FetchAll(tracks, 10, func(processed, found int, current string) {
-		update(func(s *models.TaskStatus) {
-			s.ProcessedTracks = processed
-			s.LyricsFound = found
-			s.Progress = processed * 100 / len(tracks)
-			s.CurrentTrack = current
-		})
-	})
-
-	log.Printf("[task:%s] lyrics found: %d/%d", taskID, len(lyricsMap), len(tracks))
-
-	if len(lyricsMap) == 0 {
-		setError("Could not find lyrics for any track")
+		h.failTask(ctx, taskID, "No tracks found for this period")
 		return
 	}
 
-	update(func(s *models.TaskStatus) { s.Phase = "analyzing" })
-
-	words, uniqueWords, totalWords := services.AnalyzeWords(lyricsMap, req.ExcludeStopWords)
-
-	update(func(s *models.TaskStatus) {
-		s.Phase = "done"
-		s.Progress = 100
-		s.Result = &models.TaskResult{
-			TotalScrobbles:   totalScrobbles,
-			UniqueTracks:     len(tracks),
-			LyricsFound:      len(lyricsMap),
-			LyricsMissing:    len(tracks) - len(lyricsMap),
-			TotalUniqueWords: uniqueWords,
-			TotalWordCount:   totalWords,
-			Words:            words,
-			Lyrics:           lyricsMap,
-		}
-	})
-
-	log.Printf("[task:%s] done! top word: %s (%d)",
-		taskID, words[0].Word, words[0].Count)
+	h.analyzeTracks(ctx, taskID, tracks, totalScrobbles, analysisFilters(req.AnalysisOptions))
 }
 
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -224,19 +198,86 @@ func (h *Handler) AnalyzeArtist(w http.ResponseWriter, r *http.Request) {
 	if req.MaxTracks == 0 {
 		req.MaxTracks = 200
 	}
-	req.ExcludeStopWords = true
 
 	taskID := fmt.Sprintf("%x", md5.Sum(
-		[]byte("artist_"+req.Artist),
+		[]byte("artist_"+req.Artist+filtersKey(req.AnalysisOptions)),
 	))
 
-	h.tasksMu.RLock()
-	existing, exists := h.tasks[taskID]
-	h.tasksMu.RUnlock()
+	h.startTask(w, taskID, func(ctx context.Context) { h.runArtistAnalysis(ctx, taskID, req) })
+}
 
-	if exists {
+func (h *Handler) runArtistAnalysis(ctx context.Context, taskID string, req models.ArtistAnalysisRequest) {
+	h.updateTask(ctx, taskID, func(s *models.TaskStatus) { s.Phase = "tracks" })
+
+	mb := services.NewMusicBrainz()
+
+	artistID, artistName, err := mb.FindArtist(ctx, req.Artist)
+	if err != nil {
+		h.failTask(ctx, taskID, err.Error())
+		return
+	}
+
+	tracks, err := mb.GetDiscography(ctx, artistID, artistName, req.MaxTracks)
+	if err != nil {
+		h.failTask(ctx, taskID, err.Error())
+		return
+	}
+
+	if len(tracks) == 0 {
+		h.failTask(ctx, taskID, "No tracks found for this artist")
+		return
+	}
+
+	h.analyzeTracks(ctx, taskID, tracks, 0, analysisFilters(req.AnalysisOptions))
+}
+
+func (h *Handler) AnalyzeLibrary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	if h.cfg.MusicDir == "" {
+		writeJSON(w, 400, map[string]string{"error": "MUSIC_DIR is not configured"})
+		return
+	}
+
+	var req models.LibraryAnalysisRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+
+	if req.MaxTracks == 0 {
+		req.MaxTracks = 1000
+	}
+
+	taskID := fmt.Sprintf("%x", md5.Sum(
+		[]byte("library_"+h.cfg.MusicDir+filtersKey(req.AnalysisOptions)),
+	))
+
+	h.startTask(w, taskID, func(ctx context.Context) { h.runLibraryAnalysis(ctx, taskID, req) })
+}
+
+func (h *Handler) runLibraryAnalysis(ctx context.Context, taskID string, req models.LibraryAnalysisRequest) {
+	h.updateTask(ctx, taskID, func(s *models.TaskStatus) { s.Phase = "tracks" })
+	log.Printf("[task:%s] scanning music library %s", taskID, h.cfg.MusicDir)
+
+	tracks := h.library.Tracks(ctx, req.MaxTracks)
+	if len(tracks) == 0 {
+		h.failTask(ctx, taskID, "No tagged tracks found in the music library")
+		return
+	}
+
+	h.analyzeTracks(ctx, taskID, tracks, 0, analysisFilters(req.AnalysisOptions))
+}
+
+func (h *Handler) startTask(w http.ResponseWriter, taskID string, run func(ctx context.Context)) {
+	h.tasksMu.Lock()
+	if existing, exists := h.tasks[taskID]; exists {
 		switch existing.Phase {
-		case "tracks", "lyrics", "analyzing":
+		case "pending", "tracks", "lyrics", "analyzing":
+			h.tasksMu.Unlock()
 			writeJSON(w, 200, map[string]string{
 				"task_id": taskID,
 				"status":  "already_running",
@@ -244,94 +285,256 @@ func (h *Handler) AnalyzeArtist(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-
-	h.tasksMu.Lock()
-	h.tasks[taskID] = &models.TaskStatus{ID: taskID, Phase: "pending"}
+	ctx, cancel := context.WithCancel(context.Background())
+	status := &models.TaskStatus{ID: taskID, Phase: "pending"}
+	h.tasks[taskID] = status
+	h.cancels[taskID] = cancel
+	delete(h.words, taskID)
 	h.tasksMu.Unlock()
 
-	go h.runArtistAnalysis(taskID, req)
+	go func() {
+		run(ctx)
 
+		h.tasksMu.Lock()
+		if h.tasks[taskID] == status {
+			delete(h.cancels, taskID)
+		}
+		h.tasksMu.Unlock()
+		cancel()
+	}()
 	writeJSON(w, 200, map[string]string{"task_id": taskID})
 }
 
-func (h *Handler) runArtistAnalysis(taskID string, req models.ArtistAnalysisRequest) {
-	update := func(fn func(*models.TaskStatus)) {
-		h.tasksMu.Lock()
-		if s, ok := h.tasks[taskID]; ok {
-			fn(s)
+func (h *Handler) Tasks(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/")
+	if id, sub, ok := strings.Cut(rest, "/"); ok {
+		if sub != "words" {
+			writeJSON(w, 404, map[string]string{"error": "not found"})
+			return
 		}
-		h.tasksMu.Unlock()
+		if r.Method != http.MethodGet {
+			writeJSON(w, 405, map[string]string{"error": "GET only"})
+			return
+		}
+		h.TaskWords(w, r, id)
+		return
 	}
 
-	setError := func(msg string) {
-		update(func(s *models.TaskStatus) {
-			s.Phase = "error"
-			s.Error = msg
-		})
+	taskID := extractLastSegment(r.URL.Path)
+	if taskID == "" || taskID == "tasks" {
+		writeJSON(w, 400, map[string]string{"error": "task id required"})
+		return
 	}
 
-	update(func(s *models.TaskStatus) { s.Phase = "tracks" })
+	switch r.Method {
+	case http.MethodGet:
+		h.Status(w, r)
+	case http.MethodDelete:
+		h.cancelTask(w, taskID)
+	default:
+		writeJSON(w, 405, map[string]string{"error": "GET or DELETE only"})
+	}
+}
 
-	mb := services.NewMusicBrainz()
+func (h *Handler) cancelTask(w http.ResponseWriter, taskID string) {
+	h.tasksMu.Lock()
+	defer h.tasksMu.Unlock()
 
-	artistID, artistName, err := mb.FindArtist(req.Artist)
-	if err != nil {
-		setError(err.Error())
+	status, exists := h.tasks[taskID]
+	if !exists {
+		writeJSON(w, 404, map[string]string{"error": "task not found"})
 		return
 	}
 
-	tracks, err := mb.GetDiscography(artistID, artistName, req.MaxTracks)
-	if err != nil {
-		setError(err.Error())
+	cancel, running := h.cancels[taskID]
+	if !running {
+		writeJSON(w, 409, map[string]string{"error": "task is not running", "phase": status.Phase})
 		return
 	}
 
-	if len(tracks) == 0 {
-		setError("No tracks found for this artist")
-		return
+	cancel()
+	delete(h.cancels, taskID)
+	status.Phase = "cancelled"
+	status.CurrentTrack = ""
+
+	log.Printf("[task:%s] cancelled", taskID)
+	writeJSON(w, 200, map[string]string{"task_id": taskID, "status": "cancelled"})
+}
+
+func (h *Handler) updateTask(ctx context.Context, taskID string, fn func(*models.TaskStatus)) {
+	h.tasksMu.Lock()
+	if s, ok := h.tasks[taskID]; ok && ctx.Err() == nil {
+		fn(s)
 	}
+	h.tasksMu.Unlock()
+}
 
-	update(func(s *models.TaskStatus) {
-		s.TotalTracks = len(tracks)
+func (h *Handler) failTask(ctx context.Context, taskID, msg string) {
+	h.updateTask(ctx, taskID, func(s *models.TaskStatus) {
+		s.Phase = "error"
+		s.Error = msg
 	})
+}
 
-	update(func(s *models.TaskStatus) { s.Phase = "lyrics" })
+func analysisFilters(o models.AnalysisOptions) models.AnalysisFilters {
+	f := models.AnalysisFilters{
+		ExcludeStopWords:  o.ExcludeStopWords == nil || *o.ExcludeStopWords,
+		Lang:              strings.ToLower(strings.TrimSpace(o.Lang)),
+		ExtraStopWords:    services.NormalizeWordList(o.ExtraStopWords),
+		KeepWords:         services.NormalizeWordList(o.KeepWords),
+		Stem:              o.Stem,
+		MinPhraseTracks:   o.MinPhraseTracks,
+		CollocationWindow: o.CollocationWindow,
+		CollocationWord:   services.QueryWord(o.CollocationWord),
+		Distinctive:       services.DistinctiveLogOdds,
+		TopN:              defaultTopN,
+	}
+	if o.Distinctive == services.DistinctiveTFIDF {
+		f.Distinctive = services.DistinctiveTFIDF
+	}
+	if f.MinPhraseTracks <= 0 {
+		f.MinPhraseTracks = 2
+	}
+	if f.CollocationWindow <= 0 {
+		f.CollocationWindow = 4
+	}
+	f.CollocationWindow = min(f.CollocationWindow, 10)
+	return f
+}
+
+func filtersKey(o models.AnalysisOptions) string {
+	f := analysisFilters(o)
+	return fmt.Sprintf("_%t_%s_%s_%s_%t_%d_%d_%s_%s", f.ExcludeStopWords, f.Lang,
+		strings.Join(f.ExtraStopWords, ","), strings.Join(f.KeepWords, ","), f.Stem, f.MinPhraseTracks,
+		f.CollocationWindow, f.CollocationWord, f.Distinctive)
+}
 
-	lyricsSvc := services.NewLyrics(h.cfg.GeniusToken, h.cache)
+func (h *Handler) analyzeTracks(ctx context.Context, taskID string, tracks []models.Track, totalScrobbles int, filters models.AnalysisFilters) {
+	h.updateTask(ctx, taskID, func(s *models.TaskStatus) {
+		s.TotalTracks = len(tracks)
+		s.Phase = "lyrics"
+	})
+	log.Printf("[task:%s] searching lyrics for %d tracks", taskID, len(tracks))
 
-	lyricsMap := lyricsSvc.FetchAll(tracks, 10, func(processed, found int, current string) {
-		update(func(s *models.TaskStatus) {
+	fetched := h.lyrics.FetchAll(ctx, tracks, 10, func(processed, found int, current string) {
+		h.updateTask(ctx, taskID, func(s *models.TaskStatus) {
 			s.ProcessedTracks = processed
 			s.LyricsFound = found
 			s.Progress = processed * 100 / len(tracks)
 			s.CurrentTrack = current
 		})
 	})
+	if ctx.Err() != nil {
+		return
+	}
+	lyricsMap := fetched.Lyrics
+
+	log.Printf("[task:%s] lyrics found: %d/%d", taskID, len(lyricsMap), len(tracks))
 
 	if len(lyricsMap) == 0 {
-		setError("Could not find lyrics for any track")
+		h.failTask(ctx, taskID, "Could not find lyrics for any track")
 		return
 	}
 
-	update(func(s *models.TaskStatus) { s.Phase = "analyzing" })
+	h.updateTask(ctx, taskID, func(s *models.TaskStatus) { s.Phase = "analyzing" })
+
+	deduped, duplicates, suspicious := services.DedupeLyrics(lyricsMap)
+	if len(duplicates) > 0 || len(suspicious) > 0 {
+		log.Printf("[task:%s] %d duplicate lyrics groups, %d suspicious matches",
+			taskID, len(duplicates), len(suspicious))
+	}
 
-	words, uniqueWords, totalWords := services.AnalyzeWords(lyricsMap, req.ExcludeStopWords)
+	analysis := h.analyzer.AnalyzeWords(deduped, filters)
+	words := analysis.Words
 
-	update(func(s *models.TaskStatus) {
+	h.updateTask(ctx, taskID, func(s *models.TaskStatus) {
 		s.Phase = "done"
 		s.Progress = 100
 		s.Result = &models.TaskResult{
-			TotalScrobbles:   0,
+			TotalScrobbles:   totalScrobbles,
 			UniqueTracks:     len(tracks),
 			LyricsFound:      len(lyricsMap),
-			LyricsMissing:    len(tracks) - len(lyricsMap),
-			TotalUniqueWords: uniqueWords,
-			TotalWordCount:   totalWords,
+			LyricsMissing:    len(fetched.Missing),
+			Instrumental:     fetched.Instrumental,
+			TotalUniqueWords: analysis.UniqueWords,
+			TotalWordCount:   analysis.TotalWords,
 			Words:            words,
+			Phrases:          analysis.Phrases,
+			Collocations:     analysis.Collocations,
+			WordCollocations: analysis.WordCollocations,
+			CollocationNote:  analysis.CollocationNote,
+			Distinctive:      analysis.Distinctive,
+			Stats:            analysis.Stats,
+			TrackStats:       fetched.Stats,
+			MissingTracks:    fetched.Missing,
+			Duplicates:       duplicates,
+			Suspicious:       suspicious,
+			Languages:        analysis.Languages,
+			Filters:          filters,
 			Lyrics:           lyricsMap,
 		}
+		h.words[taskID] = analysis.AllWords
+	})
+
+	if len(words) > 0 {
+		log.Printf("[task:%s] done! %d tracks, top word: %s (%d)",
+			taskID, len(tracks), words[0].Word, words[0].Count)
+	}
+}
+
+func (h *Handler) RecheckMissing(interval time.Duration, limit int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		tracks := h.mostPlayedMissing(limit)
+		if len(tracks) == 0 {
+			continue
+		}
+
+		log.Printf("[recheck] re-checking %d missing tracks", len(tracks))
+		found := h.lyrics.Recheck(context.Background(), tracks)
+		log.Printf("[recheck] found lyrics for %d/%d tracks", found, len(tracks))
+	}
+}
+
+func (h *Handler) mostPlayedMissing(limit int) []models.Track {
+	merged := make(map[string]*models.Track)
+
+	h.tasksMu.RLock()
+	for _, task := range h.tasks {
+		if task.Result == nil {
+			continue
+		}
+		for _, t := range task.Result.MissingTracks {
+			key := strings.ToLower(t.Artist + "|||" + t.Title)
+			if m, ok := merged[key]; ok {
+				m.PlayCount += t.PlayCount
+				continue
+			}
+			t := t
+			merged[key] = &t
+		}
+	}
+	h.tasksMu.RUnlock()
+
+	tracks := make([]models.Track, 0, len(merged))
+	for _, t := range merged {
+		tracks = append(tracks, *t)
+	}
+
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].PlayCount != tracks[j].PlayCount {
+			return tracks[i].PlayCount > tracks[j].PlayCount
+		}
+		if tracks[i].Artist != tracks[j].Artist {
+			return tracks[i].Artist < tracks[j].Artist
+		}
+		return tracks[i].Title < tracks[j].Title
 	})
 
-	log.Printf("[task:%s] ✅ artist %s done! %d tracks, top word: %s (%d)",
-		taskID, artistName, len(tracks), words[0].Word, words[0].Count)
+	if len(tracks) > limit {
+		tracks = tracks[:limit]
+	}
+	return tracks
 }
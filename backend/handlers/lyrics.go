@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"lastfm-lyrics/cache"
+	"lastfm-lyrics/models"
+	"lastfm-lyrics/services"
+)
+
+func (h *Handler) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.cfg.AdminToken == "" {
+			writeJSON(w, 403, map[string]string{"error": "ADMIN_TOKEN is not configured"})
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.AdminToken)) != 1 {
+			writeJSON(w, 401, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func lyricsKey(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	artist := strings.TrimSpace(r.URL.Query().Get("artist"))
+	title := strings.TrimSpace(r.URL.Query().Get("title"))
+	if artist == "" || title == "" {
+		writeJSON(w, 400, map[string]string{"error": "artist and title are required"})
+		return "", "", false
+	}
+	return artist, services.CleanTitle(title), true
+}
+
+func (h *Handler) Lyrics(w http.ResponseWriter, r *http.Request) {
+	artist, title, ok := lyricsKey(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		entry, exists := h.cache.Get(artist, title)
+		if !exists {
+			writeJSON(w, 404, map[string]string{"error": "no cached lyrics"})
+			return
+		}
+		writeJSON(w, 200, cachedLyrics(artist, title, *entry))
+
+	case http.MethodPut:
+		var req models.LyricsOverrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, 400, map[string]string{"error": "Invalid JSON"})
+			return
+		}
+
+		entry := cache.Entry{Instrumental: req.Instrumental}
+		action := "instrumental"
+		if !req.Instrumental {
+			if strings.TrimSpace(req.Lyrics) == "" {
+				writeJSON(w, 400, map[string]string{"error": "lyrics or instrumental is required"})
+				return
+			}
+			entry.Lyrics = strings.TrimSpace(req.Lyrics)
+			entry.Synced = req.Synced
+			entry.Found = true
+			action = "replace"
+		}
+
+		if err := h.cache.SetManual(artist, title, entry, action); err != nil {
+			writeJSON(w, 500, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("[lyrics] manual %s: %s — %s", action, artist, title)
+
+		saved, ok := h.cache.Get(artist, title)
+		if !ok || saved == nil {
+			writeJSON(w, 500, map[string]string{"error": "saved lyrics could not be read back"})
+			return
+		}
+		writeJSON(w, 200, cachedLyrics(artist, title, *saved))
+
+	case http.MethodDelete:
+		err := h.cache.Delete(artist, title)
+		if errors.Is(err, cache.ErrNotFound) {
+			writeJSON(w, 404, map[string]string{"error": "no cached lyrics"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, 500, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("[lyrics] manual delete: %s — %s", artist, title)
+		writeJSON(w, 200, map[string]string{"status": "deleted"})
+
+	default:
+		writeJSON(w, 405, map[string]string{"error": "GET, PUT or DELETE only"})
+	}
+}
+
+func (h *Handler) LyricsHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, 405, map[string]string{"error": "GET only"})
+		return
+	}
+
+	artist, title, ok := lyricsKey(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := h.cache.History(artist, title)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	items := make([]models.LyricsHistoryItem, 0, len(history))
+	for _, e := range history {
+		items = append(items, models.LyricsHistoryItem{
+			ID:           e.ID,
+			Action:       e.Action,
+			ChangedAt:    e.ChangedAt,
+			CachedLyrics: cachedLyrics(artist, title, e.Entry),
+		})
+	}
+	writeJSON(w, 200, items)
+}
+
+func (h *Handler) RevertLyrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	artist, title, ok := lyricsKey(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, 400, map[string]string{"error": "history id is required"})
+		return
+	}
+
+	entry, err := h.cache.Revert(artist, title, id)
+	if errors.Is(err, cache.ErrNotFound) {
+		writeJSON(w, 404, map[string]string{"error": "history entry not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	log.Printf("[lyrics] reverted %s — %s to #%d", artist, title, id)
+	writeJSON(w, 200, cachedLyrics(artist, title, *entry))
+}
+
+func cachedLyrics(artist, title string, e cache.Entry) models.CachedLyrics {
+	return models.CachedLyrics{
+		Artist:       artist,
+		Title:        title,
+		Lyrics:       e.Lyrics,
+		Synced:       e.Synced,
+		Duration:     e.Duration,
+		Source:       e.Source,
+		Confidence:   e.Confidence,
+		Found:        e.Found,
+		Instrumental: e.Instrumental,
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"lastfm-lyrics/models"
+)
+
+func (h *Handler) StopWords(w http.ResponseWriter, r *http.Request) {
+	var req models.StopWordsRequest
+	if r.Method == http.MethodPut || r.Method == http.MethodPost || r.Method == http.MethodDelete {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Words) == 0 {
+			writeJSON(w, 400, map[string]string{"error": "words are required"})
+			return
+		}
+	}
+
+	custom := h.analyzer.CustomStopWords()
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if custom, err = h.analyzer.AddStopWords(req.Words); err == nil {
+			log.Printf("[stopwords] added %v", req.Words)
+		}
+	case http.MethodDelete:
+		if custom, err = h.analyzer.RemoveStopWords(req.Words); err == nil {
+			log.Printf("[stopwords] removed %v", req.Words)
+		}
+	default:
+		writeJSON(w, 405, map[string]string{"error": "GET, PUT or DELETE only"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if custom == nil {
+		custom = []string{}
+	}
+	writeJSON(w, 200, models.StopWordsResponse{
+		Custom:    custom,
+		Languages: h.analyzer.Languages(),
+	})
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"lastfm-lyrics/models"
+)
+
+const (
+	defaultTopN     = 300
+	maxTopN         = 10000
+	defaultWordPage = 100
+	maxWordPage     = 1000
+)
+
+func (h *Handler) TaskWords(w http.ResponseWriter, r *http.Request, taskID string) {
+	h.tasksMu.RLock()
+	status, exists := h.tasks[taskID]
+	words, done := h.words[taskID]
+	phase := ""
+	if exists {
+		phase = status.Phase
+	}
+	h.tasksMu.RUnlock()
+
+	if !exists {
+		writeJSON(w, 404, map[string]string{"error": "task not found"})
+		return
+	}
+	if !done {
+		writeJSON(w, 409, map[string]string{"error": "task has no word list yet", "phase": phase})
+		return
+	}
+
+	query := r.URL.Query()
+	offset := queryInt(query.Get("offset"), 0)
+	limit := queryInt(query.Get("limit"), defaultWordPage)
+	if limit <= 0 {
+		limit = defaultWordPage
+	}
+	limit = min(limit, maxWordPage)
+	minCount := queryInt(query.Get("min_count"), 0)
+	prefix := strings.ToLower(strings.TrimSpace(query.Get("q")))
+
+	matched := make([]models.WordCount, 0)
+	for _, wc := range words {
+		if wc.Count < minCount {
+			continue
+		}
+		if prefix != "" && !wordMatches(wc, prefix) {
+			continue
+		}
+		matched = append(matched, wc)
+	}
+
+	page := models.WordPage{Total: len(matched), Offset: offset, Limit: limit, Words: []models.WordCount{}}
+	if offset < len(matched) {
+		page.Words = matched[offset:min(offset+limit, len(matched))]
+	}
+	writeJSON(w, 200, page)
+}
+
+func wordMatches(wc models.WordCount, prefix string) bool {
+	if strings.HasPrefix(wc.Word, prefix) {
+		return true
+	}
+	for _, f := range wc.Forms {
+		if strings.HasPrefix(f.Word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func queryInt(s string, def int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"lastfm-lyrics/cache"
 	"lastfm-lyrics/config"
@@ -16,11 +17,11 @@ import (
 func main() {
 	cfg := config.Load()
 
-	if cfg.LastFMKey == "" {
+	if cfg.LastFMKey == "" && !cfg.Offline {
 		log.Fatal("LASTFM_API_KEY is required. Set it in .env file.")
 	}
 
-	lyricsCache, err := cache.New(cfg.DBPath)
+	lyricsCache, err := cache.New(cfg.DBPath, cfg.MissTTL)
 	if err != nil {
 		log.Fatalf("Cache init failed: %v", err)
 	}
@@ -29,19 +30,23 @@ func main() {
 	total, found := lyricsCache.Stats()
 	log.Printf("Cache: %d entries, %d with lyrics", total, found)
 
-	services.LoadStopWords(
-		"./data/stopwords-en.json",
-		"./data/stopwords-ru.json",
-		"./data/stopwords-custom.json",
-	)
+	analyzer := services.NewAnalyzer("./data", lyricsCache.AllLyrics)
+	go analyzer.Watch(2 * time.Second)
 
-	h := handlers.New(cfg, lyricsCache)
+	services.LoadNormalizationRules("./data/normalization-rules.json")
+	services.MigrateTitleKeys(lyricsCache)
+
+	h := handlers.New(cfg, lyricsCache, analyzer)
+
+	if !cfg.Offline && cfg.RecheckInterval > 0 {
+		go h.RecheckMissing(cfg.RecheckInterval, cfg.RecheckLimit)
+	}
 
 	cors := func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", cfg.AllowOrigins)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(200)
@@ -54,8 +59,25 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/analyze", cors(h.Analyze))
 	mux.HandleFunc("/api/status/", cors(h.Status))
+	mux.HandleFunc("/api/tasks/", cors(h.Tasks))
 	mux.HandleFunc("/api/health", cors(h.Health))
+	mux.HandleFunc("/api/normalize", cors(h.Normalize))
 	mux.HandleFunc("/api/analyze-artist", cors(h.AnalyzeArtist))
+	mux.HandleFunc("/api/analyze-library", cors(h.AnalyzeLibrary))
+	mux.HandleFunc("/api/lyrics", cors(h.RequireAdmin(h.Lyrics)))
+	mux.HandleFunc("/api/lyrics/history", cors(h.RequireAdmin(h.LyricsHistory)))
+	mux.HandleFunc("/api/lyrics/revert", cors(h.RequireAdmin(h.RevertLyrics)))
+	mux.HandleFunc("/api/aliases", cors(h.RequireAdmin(h.Aliases)))
+	mux.HandleFunc("/api/stopwords", cors(h.RequireAdmin(h.StopWords)))
+
+	go func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP)
+		for range ch {
+			log.Println("SIGHUP received, reloading stop words")
+			analyzer.Reload()
+		}
+	}()
 
 	go func() {
 		ch := make(chan os.Signal, 1)
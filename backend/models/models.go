@@ -1,23 +1,82 @@
 package models
 
 type Track struct {
-	Artist    string `json:"artist"`
-	Title     string `json:"title"`
-	PlayCount int    `json:"play_count"`
+	Artist       string  `json:"artist"`
+	Title        string  `json:"title"`
+	PlayCount    int     `json:"play_count"`
+	Duration     float64 `json:"duration,omitempty"`
+	Instrumental bool    `json:"instrumental,omitempty"`
 }
 
 type WordCount struct {
-	Word   string   `json:"word"`
+	Word   string     `json:"word"`
+	Count  int        `json:"count"`
+	Tracks []string   `json:"tracks"`
+	Stem   string     `json:"stem,omitempty"`
+	Forms  []WordForm `json:"forms,omitempty"`
+}
+
+type PhraseCount struct {
+	Phrase string   `json:"phrase"`
+	Words  int      `json:"words"`
 	Count  int      `json:"count"`
 	Tracks []string `json:"tracks"`
 }
 
+type Collocation struct {
+	Words         [2]string `json:"words"`
+	Count         int       `json:"count"`
+	PMI           float64   `json:"pmi"`
+	TScore        float64   `json:"t_score"`
+	LogLikelihood float64   `json:"log_likelihood"`
+}
+
+type DistinctiveWord struct {
+	Word           string  `json:"word"`
+	Count          int     `json:"count"`
+	ReferenceCount int     `json:"reference_count"`
+	Score          float64 `json:"score"`
+}
+
+type LexicalStats struct {
+	Tokens         int          `json:"tokens"`
+	Types          int          `json:"types"`
+	TTR            float64      `json:"ttr"`
+	MTLD           float64      `json:"mtld"`
+	HDD            float64      `json:"hdd"`
+	Hapax          int          `json:"hapax_legomena"`
+	DisLegomena    int          `json:"dis_legomena"`
+	MeanWordLength float64      `json:"mean_word_length"`
+	WordsPerSong   float64      `json:"words_per_song"`
+	Zipf           float64      `json:"zipf_exponent"`
+	HeapsBeta      float64      `json:"heaps_beta"`
+	Heaps          []HeapsPoint `json:"heaps"`
+}
+
+type HeapsPoint struct {
+	Songs  int `json:"songs"`
+	Tokens int `json:"tokens"`
+	Types  int `json:"types"`
+}
+
+type WordPage struct {
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+	Words  []WordCount `json:"words"`
+}
+
+type WordForm struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
 type AnalysisRequest struct {
-	Username         string `json:"username"`
-	From             string `json:"from"`
-	To               string `json:"to"`
-	MaxTracks        int    `json:"max_tracks"`
-	ExcludeStopWords bool   `json:"exclude_stop_words"`
+	Username  string `json:"username"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	MaxTracks int    `json:"max_tracks"`
+	AnalysisOptions
 }
 
 type TaskStatus struct {
@@ -37,15 +96,136 @@ type TaskResult struct {
 	UniqueTracks     int               `json:"unique_tracks"`
 	LyricsFound      int               `json:"lyrics_found"`
 	LyricsMissing    int               `json:"lyrics_missing"`
+	Instrumental     int               `json:"instrumental"`
 	TotalUniqueWords int               `json:"total_unique_words"`
 	TotalWordCount   int               `json:"total_word_count"`
 	Words            []WordCount       `json:"words"`
+	Phrases          []PhraseCount     `json:"phrases,omitempty"`
+	Collocations     []Collocation     `json:"collocations,omitempty"`
+	WordCollocations []Collocation     `json:"word_collocations,omitempty"`
+	CollocationNote  string            `json:"collocation_note,omitempty"`
+	Distinctive      []DistinctiveWord `json:"distinctive,omitempty"`
+	Stats            *LexicalStats     `json:"stats,omitempty"`
+	TrackStats       []TrackStats      `json:"track_stats,omitempty"`
+	MissingTracks    []Track           `json:"missing_tracks,omitempty"`
+	Duplicates       []LyricsDuplicate `json:"duplicates,omitempty"`
+	Suspicious       []LyricsDuplicate `json:"suspicious_matches,omitempty"`
+	Languages        map[string]int    `json:"languages,omitempty"`
+	Filters          AnalysisFilters   `json:"filters"`
 	Lyrics           map[string]string `json:"lyrics,omitempty"`
 }
 
+type TrackStats struct {
+	Track          string  `json:"track"`
+	Source         string  `json:"source"`
+	Confidence     float64 `json:"confidence,omitempty"`
+	Synced         bool    `json:"synced"`
+	Duration       float64 `json:"duration,omitempty"`
+	WordsPerMinute float64 `json:"words_per_minute,omitempty"`
+	FirstVocal     float64 `json:"first_vocal,omitempty"`
+	VocalCoverage  float64 `json:"vocal_coverage,omitempty"`
+}
+
+type AnalysisOptions struct {
+	ExcludeStopWords  *bool    `json:"exclude_stop_words,omitempty"`
+	Lang              string   `json:"lang"`
+	ExtraStopWords    []string `json:"extra_stop_words,omitempty"`
+	KeepWords         []string `json:"keep_words,omitempty"`
+	Stem              bool     `json:"stem,omitempty"`
+	MinPhraseTracks   int      `json:"min_phrase_tracks,omitempty"`
+	CollocationWindow int      `json:"collocation_window,omitempty"`
+	CollocationWord   string   `json:"collocation_word,omitempty"`
+	Distinctive       string   `json:"distinctive"`
+}
+
+type AnalysisFilters struct {
+	ExcludeStopWords  bool     `json:"exclude_stop_words"`
+	Lang              string   `json:"lang,omitempty"`
+	ExtraStopWords    []string `json:"extra_stop_words,omitempty"`
+	KeepWords         []string `json:"keep_words,omitempty"`
+	Stem              bool     `json:"stem,omitempty"`
+	MinPhraseTracks   int      `json:"min_phrase_tracks"`
+	CollocationWindow int      `json:"collocation_window"`
+	CollocationWord   string   `json:"collocation_word,omitempty"`
+	Distinctive       string   `json:"distinctive"`
+	TopN              int      `json:"top_n"`
+}
+
 type ArtistAnalysisRequest struct {
-	Artist           string `json:"artist"`
-	MaxTracks        int    `json:"max_tracks"`
-	ExcludeStopWords bool   `json:"exclude_stop_words"`
-	Lang             string `json:"lang"`
+	Artist    string `json:"artist"`
+	MaxTracks int    `json:"max_tracks"`
+	AnalysisOptions
+}
+
+type LibraryAnalysisRequest struct {
+	MaxTracks int `json:"max_tracks"`
+	AnalysisOptions
+}
+
+type CachedLyrics struct {
+	Artist       string  `json:"artist"`
+	Title        string  `json:"title"`
+	Lyrics       string  `json:"lyrics"`
+	Synced       string  `json:"synced,omitempty"`
+	Duration     float64 `json:"duration,omitempty"`
+	Source       string  `json:"source"`
+	Confidence   float64 `json:"confidence"`
+	Found        bool    `json:"found"`
+	Instrumental bool    `json:"instrumental"`
+}
+
+type LyricsHistoryItem struct {
+	ID        int64  `json:"id"`
+	Action    string `json:"action"`
+	ChangedAt string `json:"changed_at"`
+	CachedLyrics
+}
+
+type LyricsOverrideRequest struct {
+	Lyrics       string `json:"lyrics"`
+	Synced       string `json:"synced"`
+	Instrumental bool   `json:"instrumental"`
+}
+
+type NormalizeStep struct {
+	Rule   string `json:"rule"`
+	Output string `json:"output"`
+}
+
+type Normalization struct {
+	Input  string          `json:"input"`
+	Output string          `json:"output"`
+	Steps  []NormalizeStep `json:"steps"`
+}
+
+type NormalizeResponse struct {
+	Artist Normalization `json:"artist"`
+	Title  Normalization `json:"title"`
+}
+
+type ArtistAlias struct {
+	Alias  string `json:"alias"`
+	Source string `json:"source"`
+}
+
+type ArtistAliases struct {
+	Artist  string        `json:"artist"`
+	Aliases []ArtistAlias `json:"aliases"`
+}
+
+type LyricsDuplicate struct {
+	Tracks     []string `json:"tracks"`
+	Kept       string   `json:"kept,omitempty"`
+	Dropped    []string `json:"dropped,omitempty"`
+	Similarity float64  `json:"similarity"`
+	Exact      bool     `json:"exact"`
+}
+
+type StopWordsRequest struct {
+	Words []string `json:"words"`
+}
+
+type StopWordsResponse struct {
+	Custom    []string       `json:"custom"`
+	Languages map[string]int `json:"languages"`
 }
@@ -1,85 +1,179 @@
 package services
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"sort"
 	"strings"
-	"unicode/utf8"
 
 	"lastfm-lyrics/models"
 )
 
-var (
-	sectionRe = regexp.MustCompile(`\[.*?\]`)
-	wordRe    = regexp.MustCompile(`[a-zA-Zа-яА-ЯёЁ']+`)
-)
-
-var stopWords map[string]bool
-
-func LoadStopWords(paths ...string) {
-	stopWords = make(map[string]bool)
-
-	for _, path := range paths {
-		count := loadOneFile(path)
-		log.Printf("[analyzer] loaded %d words from %s", count, path)
+var sectionRe = regexp.MustCompile(`\[.*?\]`)
+
+// NormalizeWordList tokenizes user-supplied words the way lyrics are, so
+// "Don’t" matches "don't" and "Lana Del Rey" adds lana, del and rey.
+func NormalizeWordList(words []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, entry := range words {
+		for _, w := range tokenize(entry) {
+			if !seen[w] {
+				seen[w] = true
+				out = append(out, w)
+			}
+		}
 	}
-
-	log.Printf("[analyzer] total stop words: %d", len(stopWords))
+	sort.Strings(out)
+	return out
 }
 
-func loadOneFile(path string) int {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		log.Printf("[analyzer] could not read %s: %v", path, err)
-		return 0
-	}
-
-	var words []string
-	if err := json.Unmarshal(data, &words); err != nil {
-		log.Printf("[analyzer] could not parse %s: %v", path, err)
-		return 0
-	}
-
-	count := 0
+func wordSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
 	for _, w := range words {
-		w = strings.ToLower(strings.TrimSpace(w))
-		if w != "" {
-			stopWords[w] = true
-			count++
-		}
+		set[w] = true
 	}
+	return set
+}
 
-	return count
+type Analysis struct {
+	Words            []models.WordCount
+	AllWords         []models.WordCount
+	UniqueWords      int
+	TotalWords       int
+	Languages        map[string]int
+	Phrases          []models.PhraseCount
+	Collocations     []models.Collocation
+	WordCollocations []models.Collocation
+	CollocationNote  string
+	Distinctive      []models.DistinctiveWord
+	Stats            *models.LexicalStats
 }
 
-func AnalyzeWords(lyricsMap map[string]string, excludeStop bool) ([]models.WordCount, int, int) {
+func (a *Analyzer) AnalyzeWords(lyricsMap map[string]string, f models.AnalysisFilters) *Analysis {
+	sets := a.snapshot()
+	ref := a.reference()
+	overlap := newCorpusCounts()
 	counts := make(map[string]int)
 	wordTracks := make(map[string]map[string]bool)
+	forms := make(map[string]map[string]int)
+	languages := make(map[string]int)
+	phrases := newPhraseCounter()
+	colloc := newCollocationCounter(f.CollocationWindow)
+	songs := make(map[string][]string, len(lyricsMap))
+
+	extra := wordSet(f.ExtraStopWords)
+	keep := wordSet(f.KeepWords)
+
+	requested := strings.ToLower(strings.TrimSpace(f.Lang))
+	if requested == "auto" {
+		requested = ""
+	}
+	if requested != "" && sets[requested] == nil {
+		log.Printf("[analyzer] no stop words for %q, using detected languages only", requested)
+	}
 
+	type parsedSong struct {
+		name  string
+		inRef bool
+		lines [][]string
+		words []string
+	}
+	parsed := make([]parsedSong, 0, len(lyricsMap))
+	vocab := make(map[string]bool)
 	for trackName, text := range lyricsMap {
-		text = sectionRe.ReplaceAllString(text, "")
-		words := wordRe.FindAllString(strings.ToLower(text), -1)
-
-		for _, w := range words {
-			if utf8.RuneCountInString(w) <= 1 {
-				continue
+		ps := parsedSong{name: trackName, inRef: ref.contains(text)}
+		for _, line := range strings.Split(sectionRe.ReplaceAllString(text, ""), "\n") {
+			if lt := tokenize(line); len(lt) > 0 {
+				ps.lines = append(ps.lines, lt)
+				ps.words = append(ps.words, lt...)
 			}
-			if excludeStop && stopWords[w] {
-				continue
+		}
+		if f.Stem {
+			for _, w := range ps.words {
+				vocab[w] = true
 			}
-			counts[w]++
+		}
+		parsed = append(parsed, ps)
+	}
+	stem := func(w string, langs ...string) string {
+		return stemWord(restoreDroppedG(w, vocab), langs...)
+	}
+
+	tokens := 0
+	queryKey, queryNote := "", ""
+	queryKept, queryDropped := 0, 0
+	if f.CollocationWord != "" {
+		if qt := tokenize(f.CollocationWord); len(qt) != 1 {
+			queryNote = fmt.Sprintf("%q is not a single word", f.CollocationWord)
+		} else if !countable(qt[0]) {
+			queryNote = fmt.Sprintf("%q is too short to be counted", qt[0])
+		} else {
+			queryKey = qt[0]
+			if f.Stem {
+				queryKey = stem(queryKey, requested, "en", "ru")
+			}
+		}
+	}
+
+	for _, ps := range parsed {
+		trackName, lines, words := ps.name, ps.lines, ps.words
+
+		tokens += len(words)
+		if ps.inRef {
+			overlap.add(words)
+		}
 
-			if wordTracks[w] == nil {
-				wordTracks[w] = make(map[string]bool)
+		detected := sets.detect(words)
+		if detected != "" {
+			languages[detected]++
+		}
+
+		isStop := func(w string) bool {
+			return extra[w] || (f.ExcludeStopWords && !keep[w] && sets.contains(w, requested, detected))
+		}
+		for _, line := range lines {
+			phrases.add(trackName, line, isStop)
+
+			var keys []string
+			for _, w := range line {
+				if !countable(w) {
+					continue
+				}
+				if isStop(w) {
+					if queryKey != "" && (w == queryKey || f.Stem && stem(w, detected, requested) == queryKey) {
+						queryDropped++
+					}
+					continue
+				}
+
+				key := w
+				if f.Stem {
+					key = stem(w, detected, requested)
+					if forms[key] == nil {
+						forms[key] = make(map[string]int)
+					}
+					forms[key][w]++
+				}
+				counts[key]++
+				keys = append(keys, key)
+				if key == queryKey {
+					queryKept++
+				}
+
+				if wordTracks[key] == nil {
+					wordTracks[key] = make(map[string]bool)
+				}
+				wordTracks[key][trackName] = true
 			}
-			wordTracks[w][trackName] = true
+			colloc.add(keys)
+			songs[trackName] = append(songs[trackName], keys...)
 		}
 	}
 
 	result := make([]models.WordCount, 0, len(counts))
+	display := make(map[string]string, len(counts))
 	totalWords := 0
 
 	for word, count := range counts {
@@ -89,11 +183,17 @@ func AnalyzeWords(lyricsMap map[string]string, excludeStop bool) ([]models.WordC
 		}
 		sort.Strings(tracks)
 
-		result = append(result, models.WordCount{
+		wc := models.WordCount{
 			Word:   word,
 			Count:  count,
 			Tracks: tracks,
-		})
+		}
+		if f.Stem {
+			wc.Stem = word
+			wc.Word, wc.Forms = mostFrequentForm(forms[word])
+			display[word] = wc.Word
+		}
+		result = append(result, wc)
 		totalWords += count
 	}
 
@@ -104,9 +204,39 @@ func AnalyzeWords(lyricsMap map[string]string, excludeStop bool) ([]models.WordC
 		return result[i].Word < result[j].Word
 	})
 
-	if len(result) > 300 {
-		result = result[:300]
+	distinctive := ref.distinctive(result, tokens, len(lyricsMap), overlap, f.Distinctive)
+
+	top := result
+	if f.TopN > 0 && len(top) > f.TopN {
+		top = top[:f.TopN]
 	}
 
-	return result, len(counts), totalWords
+	analysis := &Analysis{
+		Words:        top,
+		AllWords:     result,
+		UniqueWords:  len(counts),
+		TotalWords:   totalWords,
+		Languages:    languages,
+		Phrases:      phrases.top(f.MinPhraseTracks, maxPhrases),
+		Collocations: colloc.top("", maxCollocations, display),
+		Distinctive:  distinctive,
+		Stats:        lexicalStats(songs, counts),
+	}
+	if queryKey != "" {
+		analysis.WordCollocations = colloc.top(queryKey, maxCollocations, display)
+	}
+	switch {
+	case queryNote != "":
+		analysis.CollocationNote = queryNote
+	case queryKey == "" || len(analysis.WordCollocations) > 0:
+	case queryKept == 0 && queryDropped > 0 && extra[f.CollocationWord]:
+		analysis.CollocationNote = fmt.Sprintf("%q is in extra_stop_words", f.CollocationWord)
+	case queryKept == 0 && queryDropped > 0:
+		analysis.CollocationNote = fmt.Sprintf("%q is filtered out as a stop word; add it to keep_words", f.CollocationWord)
+	case queryKept == 0:
+		analysis.CollocationNote = fmt.Sprintf("%q does not occur in the analysed lyrics", f.CollocationWord)
+	default:
+		analysis.CollocationNote = fmt.Sprintf("no word co-occurs with %q often enough", f.CollocationWord)
+	}
+	return analysis
 }
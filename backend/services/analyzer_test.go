@@ -0,0 +1,56 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"lastfm-lyrics/models"
+)
+
+func TestNormalizeWordList(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{[]string{" Don’t ", "don't", "DON'T"}, []string{"don't"}},
+		{[]string{"Lana Del Rey"}, []string{"del", "lana", "rey"}},
+		{[]string{"rock-n-roll", "  ", "1999"}, []string{"rock-n-roll"}},
+		{[]string{"Nothin'"}, []string{"nothin"}},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		if got := NormalizeWordList(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NormalizeWordList(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeWordsWordFilters(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "stopwords-en.json"), []byte(`["the", "you"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a := NewAnalyzer(dir, nil)
+	if _, err := a.AddStopWords([]string{"Lana Del Rey"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lyrics := map[string]string{"Lana Del Rey — Song": "don’t you love the summer, Lana?\ndon't you cry, Rey"}
+	f := models.AnalysisFilters{
+		ExcludeStopWords: true,
+		Lang:             "en",
+		ExtraStopWords:   NormalizeWordList([]string{"Don’t"}),
+		KeepWords:        NormalizeWordList([]string{" YOU "}),
+	}
+
+	got := make(map[string]int)
+	for _, wc := range a.AnalyzeWords(lyrics, f).Words {
+		got[wc.Word] = wc.Count
+	}
+	want := map[string]int{"you": 2, "love": 1, "summer": 1, "cry": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("counts = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	reContributors = regexp.MustCompile(`(?i)^\d+\s*contributors?.*?lyrics`)
+	reLyricsHeader = regexp.MustCompile(`(?i)^.{0,200}\blyrics$`)
+	reReadMore     = regexp.MustCompile(`(?i)read more\s*$`)
+	reMightLike    = regexp.MustCompile(`(?i)you might also like`)
+	reEmbed        = regexp.MustCompile(`(?i)\d*\s*embed$`)
+	reSeeLive      = regexp.MustCompile(`(?i)^see .+ live(get tickets.*)?$|^get tickets as low as`)
+	reTicketAd     = regexp.MustCompile(`(?i)^see .+ liveget tickets as low as|^get tickets as low as \$`)
+	reLRCMeta      = regexp.MustCompile(`(?i)^\[(ar|ti|al|au|by|length|offset|re|ve|#):.*\]$`)
+	reLRCStamp     = regexp.MustCompile(`\[\d+:\d{1,2}(?:[.:]\d{1,3})?\]|<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+	reCredit       = regexp.MustCompile(
+		`(?i)^(produced|written|composed|arranged|mixed|mastered|recorded|engineered|lyrics|music|words)` +
+			`( and [a-z]+)? by\b|^(producers?|writers?|songwriters?|composers?)\s*:`)
+	reCreditsHeader = regexp.MustCompile(`(?i)^\[?\s*(credits|кредиты)\s*\]?$`)
+	reSectionHeader = regexp.MustCompile(`^\[.*\]$`)
+)
+
+// cleanLyrics normalizes provider text before caching. LRC tags, credits
+// and repeated section headers are removed for every source; Genius page
+// chrome only for Genius, since elsewhere a first line equal to the title
+// or ending in "lyrics" is a real lyric.
+func cleanLyrics(text, title, source string) string {
+	genius := source == "genius"
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+
+	out := make([]string, 0, len(lines))
+	inHeader := true
+	inPreamble := true
+	inCredits := false
+	folded := foldName(title)
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if reLRCMeta.MatchString(line) {
+			continue
+		}
+		line = strings.TrimSpace(reLRCStamp.ReplaceAllString(line, ""))
+
+		if genius {
+			if i == 0 || inHeader {
+				line = strings.TrimSpace(reContributors.ReplaceAllString(line, ""))
+			}
+			line = strings.TrimSpace(reMightLike.ReplaceAllString(line, ""))
+		}
+
+		if inCredits {
+			if line == "" || reSectionHeader.MatchString(line) && !reCreditsHeader.MatchString(line) {
+				inCredits = false
+			} else {
+				continue
+			}
+		}
+
+		switch {
+		case line == "":
+		case reCreditsHeader.MatchString(line):
+			inCredits = true
+			continue
+		case inPreamble && reCredit.MatchString(line):
+			continue
+		case !genius:
+		case reTicketAd.MatchString(line):
+			continue
+		case inPreamble && reSeeLive.MatchString(line):
+			continue
+		case inHeader && (reLyricsHeader.MatchString(line) || reReadMore.MatchString(line)):
+			continue
+		case inHeader && folded != "" && foldName(line) == folded:
+			continue
+		}
+
+		if line != "" {
+			inPreamble = false
+			if !reSectionHeader.MatchString(line) {
+				inHeader = false
+			}
+		}
+
+		if reSectionHeader.MatchString(line) && len(out) > 0 && out[len(out)-1] == line {
+			continue
+		}
+
+		out = append(out, line)
+	}
+
+	// Credits and tour ads are only stripped outside the lyrics body:
+	// "Written by…" or "See you live" can be a real lyric line.
+	for len(out) > 0 {
+		last := out[len(out)-1]
+		if genius {
+			last = strings.TrimSpace(reEmbed.ReplaceAllString(last, ""))
+		}
+		if last != "" && !reCredit.MatchString(last) && !(genius && reSeeLive.MatchString(last)) {
+			out[len(out)-1] = last
+			break
+		}
+		out = out[:len(out)-1]
+	}
+
+	var sb strings.Builder
+	blank := 0
+	for _, line := range out {
+		if line == "" {
+			blank++
+			continue
+		}
+		if sb.Len() > 0 {
+			if blank > 0 {
+				sb.WriteString("\n\n")
+			} else {
+				sb.WriteString("\n")
+			}
+		}
+		blank = 0
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
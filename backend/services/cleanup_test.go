@@ -0,0 +1,79 @@
+package services
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestCleanLyricsGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		title  string
+	}{
+		{"header-chrome", "genius", "Northern Road"},
+		{"body-credits", "genius", "Written By The Sea"},
+		{"ticket-ad", "genius", "Белая ночь"},
+		{"lrclib-title-line", "lrclib", "Say My Name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var text string
+			if tt.source == "genius" {
+				in, err := os.Open(filepath.Join("testdata", "cleanup", tt.name+".html"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer in.Close()
+				text = parseGeniusHTML(in)
+			} else {
+				raw, err := os.ReadFile(filepath.Join("testdata", "cleanup", tt.name+".lrc"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				text = string(raw)
+			}
+
+			got := cleanLyrics(text, tt.title, tt.source) + "\n"
+
+			golden := filepath.Join("testdata", "cleanup", tt.name+".txt")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("cleanLyrics mismatch\n--- got ---\n%s--- want ---\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestCleanLyricsLRC(t *testing.T) {
+	in := "[ar:Arctic Lights]\n[ti:Northern Road]\n[length:03:41]\n" +
+		"[00:12.40]Headlights on the northern road\n" +
+		"[00:16.85]<00:16.85>Snow is <00:17.90>falling\n" +
+		"[00:21.02][01:40.11]Carry me home\n"
+	want := "Headlights on the northern road\nSnow is falling\nCarry me home"
+
+	if got := cleanLyrics(in, "Northern Road", "lrclib"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCleanLyricsKeepsBodyCredits(t *testing.T) {
+	in := "Intro line\nWritten by the light of the moon\nSee you live in my dreams\nOutro line"
+	if got := cleanLyrics(in, "", "genius"); !strings.Contains(got, "Written by the light") || !strings.Contains(got, "See you live") {
+		t.Errorf("body lines dropped: %q", got)
+	}
+}
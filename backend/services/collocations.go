@@ -0,0 +1,121 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"lastfm-lyrics/models"
+)
+
+const (
+	minCollocationCount = 3
+	maxCollocations     = 50
+)
+
+type wordPair [2]string
+
+func makePair(a, b string) wordPair {
+	if b < a {
+		a, b = b, a
+	}
+	return wordPair{a, b}
+}
+
+// collocationCounter counts unordered pairs of content words that occur
+// within window positions of each other on the same line.
+type collocationCounter struct {
+	window int
+	pairs  map[wordPair]int
+	slots  map[string]int
+	total  int
+}
+
+func newCollocationCounter(window int) *collocationCounter {
+	return &collocationCounter{
+		window: window,
+		pairs:  make(map[wordPair]int),
+		slots:  make(map[string]int),
+	}
+}
+
+func (c *collocationCounter) add(line []string) {
+	for i, a := range line {
+		for j := i + 1; j < len(line) && j <= i+c.window; j++ {
+			b := line[j]
+			if a == b {
+				continue
+			}
+			c.pairs[makePair(a, b)]++
+			c.slots[a]++
+			c.slots[b]++
+			c.total++
+		}
+	}
+}
+
+// score fills PMI, t-score and Dunning's log-likelihood from the 2x2
+// contingency table of pair slots containing a and/or b.
+func (c *collocationCounter) score(p wordPair, o11 int) models.Collocation {
+	n := float64(c.total)
+	ra, rb := float64(c.slots[p[0]]), float64(c.slots[p[1]])
+	o := float64(o11)
+
+	cells := [4]float64{o, ra - o, rb - o, n - ra - rb + o}
+	expected := [4]float64{
+		ra * rb / n,
+		ra * (n - rb) / n,
+		(n - ra) * rb / n,
+		(n - ra) * (n - rb) / n,
+	}
+
+	g2 := 0.0
+	for i, obs := range cells {
+		if obs > 0 && expected[i] > 0 {
+			g2 += obs * math.Log(obs/expected[i])
+		}
+	}
+
+	return models.Collocation{
+		Words:         p,
+		Count:         o11,
+		PMI:           round2(math.Log2(o / expected[0])),
+		TScore:        round2((o - expected[0]) / math.Sqrt(o)),
+		LogLikelihood: round2(2 * g2),
+	}
+}
+
+func (c *collocationCounter) top(word string, limit int, display map[string]string) []models.Collocation {
+	var result []models.Collocation
+	for p, count := range c.pairs {
+		if count < minCollocationCount {
+			continue
+		}
+		if word != "" && p[0] != word && p[1] != word {
+			continue
+		}
+		// G² is large for pairs that avoid each other too, so only keep
+		// pairs seen more often than expected.
+		if col := c.score(p, count); col.PMI > 0 {
+			result = append(result, col)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].LogLikelihood != result[j].LogLikelihood {
+			return result[i].LogLikelihood > result[j].LogLikelihood
+		}
+		return result[i].Count > result[j].Count
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	for i := range result {
+		for k, w := range result[i].Words {
+			if d, ok := display[w]; ok {
+				result[i].Words[k] = d
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+
+	"lastfm-lyrics/models"
+)
+
+const (
+	shingleSize    = 3
+	minHashes      = 64
+	lshBands       = 16
+	lshRows        = minHashes / lshBands
+	nearDuplicate  = 0.8
+	minDedupeWords = 8
+	sameTitle      = 0.85
+)
+
+type fingerprint struct {
+	key    string
+	artist string
+	title  string
+	exact  uint64
+	sig    [minHashes]uint64
+}
+
+func fingerprintLyrics(key, text string) *fingerprint {
+	words := tokenize(sectionRe.ReplaceAllString(text, ""))
+	if len(words) < minDedupeWords {
+		return nil
+	}
+
+	artist, title := "", key
+	if i := strings.Index(key, " — "); i >= 0 {
+		artist, title = key[:i], key[i+len(" — "):]
+	}
+	fp := &fingerprint{key: key, artist: foldName(artist), title: foldName(cleanTitle(title))}
+
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(words, " ")))
+	fp.exact = h.Sum64()
+
+	for i := range fp.sig {
+		fp.sig[i] = math.MaxUint64
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h.Reset()
+		h.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		x := h.Sum64()
+		for j := range fp.sig {
+			if v := mix64(x ^ uint64(j+1)*0x9e3779b97f4a7c15); v < fp.sig[j] {
+				fp.sig[j] = v
+			}
+		}
+	}
+	return fp
+}
+
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func (a *fingerprint) similarity(b *fingerprint) float64 {
+	if a.exact == b.exact {
+		return 1
+	}
+	same := 0
+	for i := range a.sig {
+		if a.sig[i] == b.sig[i] {
+			same++
+		}
+	}
+	return float64(same) / minHashes
+}
+
+func DedupeLyrics(lyricsMap map[string]string) (map[string]string, []models.LyricsDuplicate, []models.LyricsDuplicate) {
+	keys := make([]string, 0, len(lyricsMap))
+	for k := range lyricsMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var fps []*fingerprint
+	for _, k := range keys {
+		if fp := fingerprintLyrics(k, lyricsMap[k]); fp != nil {
+			fps = append(fps, fp)
+		}
+	}
+
+	parent := make([]int, len(fps))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	buckets := make(map[[lshRows + 1]uint64][]int)
+	for i, fp := range fps {
+		for b := 0; b < lshBands; b++ {
+			var band [lshRows + 1]uint64
+			band[0] = uint64(b)
+			copy(band[1:], fp.sig[b*lshRows:(b+1)*lshRows])
+			buckets[band] = append(buckets[band], i)
+		}
+	}
+	for _, members := range buckets {
+		for x, i := range members {
+			for _, j := range members[x+1:] {
+				if find(i) != find(j) && fps[i].similarity(fps[j]) >= nearDuplicate {
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+	}
+
+	components := make(map[int][]*fingerprint)
+	for i, fp := range fps {
+		root := find(i)
+		components[root] = append(components[root], fp)
+	}
+
+	deduped := make(map[string]string, len(lyricsMap))
+	for k, v := range lyricsMap {
+		deduped[k] = v
+	}
+
+	var duplicates, suspicious []models.LyricsDuplicate
+	for _, comp := range components {
+		if len(comp) < 2 {
+			continue
+		}
+
+		var songs [][]*fingerprint
+		for _, fp := range comp {
+			placed := false
+			for i, song := range songs {
+				if similarity(song[0].title, fp.title) >= sameTitle {
+					songs[i] = append(song, fp)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				songs = append(songs, []*fingerprint{fp})
+			}
+		}
+
+		// Only an exact copy of the same artist's same song is dropped.
+		// Near matches (live edits, covers) are listed but still counted,
+		// and nothing is dropped from a group that spans unrelated titles.
+		for _, song := range songs {
+			if len(song) < 2 {
+				continue
+			}
+			dup := models.LyricsDuplicate{Similarity: 1, Exact: true}
+			for i, fp := range song {
+				dup.Tracks = append(dup.Tracks, fp.key)
+				if i == 0 {
+					continue
+				}
+				dup.Similarity = math.Min(dup.Similarity, song[0].similarity(fp))
+				dup.Exact = dup.Exact && fp.exact == song[0].exact
+
+				if len(songs) > 1 {
+					continue
+				}
+				for _, kept := range song[:i] {
+					if kept.artist == fp.artist && kept.title == fp.title && kept.exact == fp.exact {
+						delete(deduped, fp.key)
+						if dup.Kept == "" {
+							dup.Kept = kept.key
+						}
+						dup.Dropped = append(dup.Dropped, fp.key)
+						break
+					}
+				}
+			}
+			dup.Similarity = round2(dup.Similarity)
+			duplicates = append(duplicates, dup)
+		}
+
+		if len(songs) > 1 {
+			s := models.LyricsDuplicate{Similarity: 1, Exact: true}
+			for _, song := range songs {
+				for _, fp := range song {
+					s.Tracks = append(s.Tracks, fp.key)
+				}
+				if song[0] != songs[0][0] {
+					s.Similarity = math.Min(s.Similarity, songs[0][0].similarity(song[0]))
+					s.Exact = s.Exact && song[0].exact == songs[0][0].exact
+				}
+			}
+			s.Similarity = round2(s.Similarity)
+			suspicious = append(suspicious, s)
+		}
+	}
+
+	byFirst := func(d []models.LyricsDuplicate) {
+		sort.Slice(d, func(i, j int) bool { return d[i].Tracks[0] < d[j].Tracks[0] })
+	}
+	byFirst(duplicates)
+	byFirst(suspicious)
+
+	return deduped, duplicates, suspicious
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const dedupeVerse = "we were running through the city lights tonight\n" +
+	"every window burning like a thousand tiny suns\n" +
+	"hold my hand and never let the morning find us\n" +
+	"we were young and reckless and the road was ours\n" +
+	"sing it louder let the echo carry us away\n" +
+	"nothing ever lasts but this will last forever"
+
+func TestDedupeLyrics(t *testing.T) {
+	near := strings.Replace(dedupeVerse, "lasts but this will last forever", "lasts but this moment stays", 1)
+
+	tests := []struct {
+		name       string
+		lyrics     map[string]string
+		dropped    []string
+		duplicates int
+		suspicious int
+	}{
+		{
+			name: "exact copy of the same song is dropped",
+			lyrics: map[string]string{
+				"Band — Night Run":                 dedupeVerse,
+				"Band — Night Run (2011 Remaster)": dedupeVerse,
+			},
+			dropped:    []string{"Band — Night Run (2011 Remaster)"},
+			duplicates: 1,
+		},
+		{
+			name: "near copy of the same song is only reported",
+			lyrics: map[string]string{
+				"Band — Night Run":        dedupeVerse,
+				"Band — Night Run (Live)": near,
+			},
+			duplicates: 1,
+		},
+		{
+			name: "cover by another artist is kept",
+			lyrics: map[string]string{
+				"Band — Night Run":       dedupeVerse,
+				"Other Band — Night Run": dedupeVerse,
+			},
+			duplicates: 1,
+		},
+		{
+			name: "unrelated titles sharing a text are flagged, nothing dropped",
+			lyrics: map[string]string{
+				"Band — Night Run":                 dedupeVerse,
+				"Band — Night Run (2011 Remaster)": dedupeVerse,
+				"Band — Paper Boats":               dedupeVerse,
+			},
+			duplicates: 1,
+			suspicious: 1,
+		},
+		{
+			name: "different texts are left alone",
+			lyrics: map[string]string{
+				"Band — Night Run":   dedupeVerse,
+				"Band — Paper Boats": "paper boats are drifting down the river to the sea and we just watch them go away",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deduped, duplicates, suspicious := DedupeLyrics(tt.lyrics)
+
+			var dropped []string
+			for k := range tt.lyrics {
+				if _, ok := deduped[k]; !ok {
+					dropped = append(dropped, k)
+				}
+			}
+			sort.Strings(dropped)
+			if !reflect.DeepEqual(dropped, tt.dropped) {
+				t.Errorf("dropped %q, want %q", dropped, tt.dropped)
+			}
+			if len(duplicates) != tt.duplicates {
+				t.Errorf("got %d duplicate groups, want %d: %+v", len(duplicates), tt.duplicates, duplicates)
+			}
+			if len(suspicious) != tt.suspicious {
+				t.Errorf("got %d suspicious groups, want %d: %+v", len(suspicious), tt.suspicious, suspicious)
+			}
+		})
+	}
+}
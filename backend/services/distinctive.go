@@ -0,0 +1,177 @@
+package services
+
+import (
+	"hash/fnv"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"lastfm-lyrics/models"
+)
+
+const (
+	referenceTTL       = time.Hour
+	minReferenceRetry  = time.Minute
+	minReferenceDocs   = 20
+	minDistinctiveHits = 3
+	maxDistinctive     = 100
+	logOddsPrior       = 1000.0
+)
+
+const (
+	DistinctiveLogOdds = "log_odds"
+	DistinctiveTFIDF   = "tfidf"
+)
+
+// corpusCounts are raw token frequencies over a set of songs.
+type corpusCounts struct {
+	docs  int
+	total int
+	tf    map[string]int
+	df    map[string]int
+}
+
+func newCorpusCounts() *corpusCounts {
+	return &corpusCounts{tf: make(map[string]int), df: make(map[string]int)}
+}
+
+func (c *corpusCounts) add(words []string) {
+	seen := make(map[string]bool)
+	for _, w := range words {
+		c.tf[w]++
+		c.total++
+		if !seen[w] {
+			seen[w] = true
+			c.df[w]++
+		}
+	}
+	c.docs++
+}
+
+// referenceCorpus holds word frequencies over every cached lyric, plus a
+// hash of each text so analysed songs can be matched against it. It is
+// never modified once built.
+type referenceCorpus struct {
+	*corpusCounts
+	songs map[uint64]bool
+}
+
+func lyricsHash(text string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return h.Sum64()
+}
+
+func (ref *referenceCorpus) contains(text string) bool {
+	return ref != nil && ref.songs[lyricsHash(text)]
+}
+
+// reference returns the current corpus without waiting for it. A stale or
+// missing corpus is rebuilt in the background; analyses keep using the old
+// one meanwhile and get no distinctive words until the first build lands.
+func (a *Analyzer) reference() *referenceCorpus {
+	if a.lyricsSource == nil {
+		return nil
+	}
+
+	a.refMu.Lock()
+	defer a.refMu.Unlock()
+
+	if !a.refBuilding && !time.Now().Before(a.refNext) {
+		a.refBuilding = true
+		go a.buildReference()
+	}
+	return a.ref
+}
+
+func (a *Analyzer) buildReference() {
+	texts, err := a.lyricsSource()
+
+	var ref *referenceCorpus
+	if err == nil {
+		ref = &referenceCorpus{corpusCounts: newCorpusCounts(), songs: make(map[uint64]bool, len(texts))}
+		for _, text := range texts {
+			ref.songs[lyricsHash(text)] = true
+			ref.add(tokenize(sectionRe.ReplaceAllString(text, "")))
+		}
+	}
+
+	a.refMu.Lock()
+	defer a.refMu.Unlock()
+	a.refBuilding = false
+
+	if err != nil {
+		a.refRetry = min(max(2*a.refRetry, minReferenceRetry), referenceTTL)
+		a.refNext = time.Now().Add(a.refRetry)
+		log.Printf("[analyzer] could not load reference corpus, retrying in %s: %v", a.refRetry, err)
+		return
+	}
+
+	log.Printf("[analyzer] reference corpus: %d songs, %d words, %d types", ref.docs, ref.total, len(ref.tf))
+	a.ref = ref
+	a.refRetry = 0
+	a.refNext = time.Now().Add(referenceTTL)
+}
+
+// distinctive ranks words against the reference corpus. The analysed songs
+// that are already in the corpus (overlap) are subtracted from it first;
+// songs cached after the corpus was built are not in it to begin with.
+func (ref *referenceCorpus) distinctive(words []models.WordCount, tokens, docs int, overlap *corpusCounts, method string) []models.DistinctiveWord {
+	if ref == nil || ref.docs < minReferenceDocs || tokens == 0 {
+		return nil
+	}
+
+	refTotal := math.Max(float64(ref.total-overlap.total), 1)
+	refDocs := max(ref.docs-overlap.docs, 1)
+
+	var result []models.DistinctiveWord
+	for _, wc := range words {
+		if wc.Count < minDistinctiveHits || (docs > 1 && len(wc.Tracks) < 2) {
+			continue
+		}
+
+		refCount, refDF := 0, 0
+		if len(wc.Forms) > 0 {
+			for _, form := range wc.Forms {
+				refCount += ref.tf[form.Word] - overlap.tf[form.Word]
+				refDF = max(refDF, ref.df[form.Word]-overlap.df[form.Word])
+			}
+		} else {
+			refCount = ref.tf[wc.Word] - overlap.tf[wc.Word]
+			refDF = ref.df[wc.Word] - overlap.df[wc.Word]
+		}
+
+		y, r := float64(wc.Count), float64(refCount)
+		var score float64
+		switch method {
+		case DistinctiveTFIDF:
+			idf := math.Log(float64(refDocs+1)/float64(refDF+1)) + 1
+			score = y / float64(tokens) * 1000 * idf
+		default:
+			alpha := logOddsPrior * (r + 1) / (refTotal + float64(len(ref.tf)))
+			delta := math.Log((y+alpha)/(float64(tokens)+logOddsPrior-y-alpha)) -
+				math.Log((r+alpha)/(refTotal+logOddsPrior-r-alpha))
+			score = delta / math.Sqrt(1/(y+alpha)+1/(r+alpha))
+		}
+
+		result = append(result, models.DistinctiveWord{
+			Word:           wc.Word,
+			Count:          wc.Count,
+			ReferenceCount: refCount,
+			Score:          round2(score),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Word < result[j].Word
+	})
+
+	if len(result) > maxDistinctive {
+		result = result[:maxDistinctive]
+	}
+	return result
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"lastfm-lyrics/models"
+)
+
+const (
+	mtldThreshold  = 0.72
+	hddSample      = 42
+	maxHeapsPoints = 100
+)
+
+// lexicalStats describes the counted tokens of each song, taken in track
+// order so the vocabulary growth curve is reproducible.
+func lexicalStats(songs map[string][]string, counts map[string]int) *models.LexicalStats {
+	names := make([]string, 0, len(songs))
+	for name := range songs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var stream []string
+	var heaps []models.HeapsPoint
+	seen := make(map[string]bool)
+	runes := 0
+	for i, name := range names {
+		for _, w := range songs[name] {
+			stream = append(stream, w)
+			seen[w] = true
+			runes += utf8.RuneCountInString(w)
+		}
+		heaps = append(heaps, models.HeapsPoint{Songs: i + 1, Tokens: len(stream), Types: len(seen)})
+	}
+
+	if len(stream) == 0 {
+		return nil
+	}
+
+	stats := &models.LexicalStats{
+		Tokens:         len(stream),
+		Types:          len(counts),
+		TTR:            round4(float64(len(counts)) / float64(len(stream))),
+		MTLD:           round2(mtld(stream)),
+		HDD:            round4(hdd(counts, len(stream))),
+		MeanWordLength: round2(float64(runes) / float64(len(stream))),
+		WordsPerSong:   round2(float64(len(stream)) / float64(len(names))),
+		Zipf:           round4(zipfExponent(counts)),
+		HeapsBeta:      round4(heapsBeta(heaps)),
+		Heaps:          sampleHeaps(heaps),
+	}
+	for _, c := range counts {
+		switch c {
+		case 1:
+			stats.Hapax++
+		case 2:
+			stats.DisLegomena++
+		}
+	}
+	return stats
+}
+
+func mtld(tokens []string) float64 {
+	reversed := make([]string, len(tokens))
+	for i, t := range tokens {
+		reversed[len(tokens)-1-i] = t
+	}
+	return (mtldPass(tokens) + mtldPass(reversed)) / 2
+}
+
+func mtldPass(tokens []string) float64 {
+	factors := 0.0
+	types := make(map[string]bool)
+	n := 0
+	for _, t := range tokens {
+		n++
+		types[t] = true
+		if float64(len(types))/float64(n) <= mtldThreshold {
+			factors++
+			types = make(map[string]bool)
+			n = 0
+		}
+	}
+	if n > 0 {
+		factors += (1 - float64(len(types))/float64(n)) / (1 - mtldThreshold)
+	}
+	if factors == 0 {
+		return float64(len(tokens))
+	}
+	return float64(len(tokens)) / factors
+}
+
+// hdd is the mean chance of seeing each type in a random 42-token sample,
+// summed over all types.
+func hdd(counts map[string]int, total int) float64 {
+	n := min(hddSample, total)
+	lnAll := lnChoose(total, n)
+
+	sum := 0.0
+	for _, f := range counts {
+		p0 := 0.0
+		if total-f >= n {
+			p0 = math.Exp(lnChoose(total-f, n) - lnAll)
+		}
+		sum += (1 - p0) / float64(n)
+	}
+	return sum
+}
+
+func lnChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// zipfExponent fits log(count) against log(rank). The hapax tail is left
+// out when there are enough repeated words, as its flat plateau skews the
+// slope.
+func zipfExponent(counts map[string]int) float64 {
+	freqs := make([]int, 0, len(counts))
+	for _, c := range counts {
+		freqs = append(freqs, c)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(freqs)))
+
+	n := len(freqs)
+	for i, f := range freqs {
+		if f < 2 {
+			if i >= 10 {
+				n = i
+			}
+			break
+		}
+	}
+
+	var xs, ys []float64
+	for i, f := range freqs[:n] {
+		xs = append(xs, math.Log(float64(i+1)))
+		ys = append(ys, math.Log(float64(f)))
+	}
+	return -slope(xs, ys)
+}
+
+func heapsBeta(points []models.HeapsPoint) float64 {
+	var xs, ys []float64
+	for _, p := range points {
+		if p.Tokens > 0 && p.Types > 0 {
+			xs = append(xs, math.Log(float64(p.Tokens)))
+			ys = append(ys, math.Log(float64(p.Types)))
+		}
+	}
+	return slope(xs, ys)
+}
+
+func slope(xs, ys []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	n := float64(len(xs))
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / d
+}
+
+func sampleHeaps(points []models.HeapsPoint) []models.HeapsPoint {
+	if len(points) <= maxHeapsPoints {
+		return points
+	}
+	sampled := make([]models.HeapsPoint, 0, maxHeapsPoints)
+	for i := 0; i < maxHeapsPoints-1; i++ {
+		sampled = append(sampled, points[i*len(points)/(maxHeapsPoints-1)])
+	}
+	return append(sampled, points[len(points)-1])
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+type geniusSearch struct {
+	Response struct {
+		Hits []geniusHit `json:"hits"`
+	} `json:"response"`
+}
+
+type geniusHit struct {
+	Type   string `json:"type"`
+	Result struct {
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		PrimaryArtist struct {
+			Name string `json:"name"`
+		} `json:"primary_artist"`
+	} `json:"result"`
+}
+
+var geniusTranslationRe = regexp.MustCompile(
+	`(?i)translation|traducci|traduç|traduz|übersetzung|перевод|çeviri|tłumaczenie|romanized`)
+
+func (s *Lyrics) tryGenius(ctx context.Context, artist, title string) lookupResult {
+
+	query := artist + " " + title
+	params := url.Values{"q": {query}}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET",
+		"https://api.genius.com/search?"+params.Encode(), nil)
+	req.Header.Set("Authorization", "Bearer "+s.geniusToken)
+
+	var search geniusSearch
+	if err := s.getJSON(req, &search); err != nil {
+		return lookupFailed(err)
+	}
+
+	type candidate struct {
+		url   string
+		score float64
+	}
+	var candidates []candidate
+
+	for _, hit := range search.Response.Hits {
+		if !isGeniusSong(hit) {
+			continue
+		}
+		score := matchScore(artist, title, 0, hit.Result.PrimaryArtist.Name, hit.Result.Title, 0)
+		if score < minMatchConfidence {
+			continue
+		}
+		candidates = append(candidates, candidate{hit.Result.URL, score})
+	}
+
+	if len(candidates) == 0 {
+		if len(search.Response.Hits) > 0 {
+			log.Printf("[lyrics] genius: no matching hit among %d for %s — %s",
+				len(search.Response.Hits), artist, title)
+		}
+		return lookupResult{Status: lookupNotFound}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	var lastErr error
+	for _, c := range candidates {
+		if !sleepCtx(ctx, 300*time.Millisecond) {
+			return lookupFailed(ctx.Err())
+		}
+
+		lyrics, err := s.scrapeGenius(ctx, c.url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if lyrics != "" {
+			return lookupResult{Status: lookupFound, Lyrics: lyrics, Confidence: c.score}
+		}
+	}
+
+	if lastErr != nil {
+		return lookupFailed(lastErr)
+	}
+	return lookupResult{Status: lookupNotFound}
+}
+
+func isGeniusSong(hit geniusHit) bool {
+	if hit.Type != "song" {
+		return false
+	}
+	r := hit.Result
+	if !strings.HasSuffix(r.URL, "-lyrics") {
+		return false
+	}
+	if strings.HasPrefix(r.PrimaryArtist.Name, "Genius") {
+		return false
+	}
+	return !geniusTranslationRe.MatchString(r.Title) &&
+		!geniusTranslationRe.MatchString(r.PrimaryArtist.Name)
+}
+
+func (s *Lyrics) scrapeGenius(ctx context.Context, songURL string) (string, error) {
+	pageReq, _ := http.NewRequestWithContext(ctx, "GET", songURL, nil)
+	pageReq.Header.Set("User-Agent",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+
+	pageResp, err := s.client.Do(pageReq)
+	if err != nil {
+		return "", &transientError{err: err, retryable: true}
+	}
+	defer pageResp.Body.Close()
+
+	if err := checkStatus(pageResp); err != nil {
+		return "", err
+	}
+
+	return parseGeniusHTML(pageResp.Body), nil
+}
+
+func parseGeniusHTML(r io.Reader) string {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+
+		if n.Type == html.ElementNode && n.Data == "div" {
+			for _, a := range n.Attr {
+				if a.Key == "data-lyrics-container" && a.Val == "true" {
+					getText(n, &sb)
+					sb.WriteString("\n")
+					return
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+
+	find(doc)
+	return strings.TrimSpace(sb.String())
+}
+
+func getText(n *html.Node, sb *strings.Builder) {
+	if n.Type == html.ElementNode {
+		for _, a := range n.Attr {
+			if a.Key == "data-exclude-from-selection" && a.Val == "true" {
+				return
+			}
+		}
+	}
+	if n.Type == html.TextNode {
+		sb.WriteString(n.Data)
+	}
+	if n.Type == html.ElementNode && n.Data == "br" {
+		sb.WriteString("\n")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		getText(c, sb)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -48,7 +49,7 @@ type lfmTrack struct {
 	} `json:"@attr"`
 }
 
-func (s *LastFM) GetTracks(username, from, to string, maxTracks int) ([]models.Track, int, error) {
+func (s *LastFM) GetTracks(ctx context.Context, username, from, to string, maxTracks int) ([]models.Track, int, error) {
 
 	fromTs, err := toTimestamp(from)
 	if err != nil {
@@ -84,7 +85,8 @@ func (s *LastFM) GetTracks(username, from, to string, maxTracks int) ([]models.T
 
 		apiURL := "https://ws.audioscrobbler.com/2.0/?" + params.Encode()
 
-		resp, err := s.client.Get(apiURL)
+		req, _ := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		resp, err := s.client.Do(req)
 		if err != nil {
 			return nil, 0, fmt.Errorf("lastfm request failed: %w", err)
 		}
@@ -136,7 +138,9 @@ func (s *LastFM) GetTracks(username, from, to string, maxTracks int) ([]models.T
 		}
 
 		page++
-		time.Sleep(200 * time.Millisecond)
+		if !sleepCtx(ctx, 200*time.Millisecond) {
+			return nil, 0, ctx.Err()
+		}
 	}
 
 	totalScrobbles := len(all)
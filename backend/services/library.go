@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"lastfm-lyrics/models"
+)
+
+var audioExts = map[string]bool{
+	".mp3": true, ".flac": true, ".ogg": true, ".oga": true,
+	".opus": true, ".m4a": true, ".mp4": true, ".aac": true, ".alac": true,
+}
+
+type Library struct {
+	dir string
+
+	scanMu  sync.Mutex // serializes walks; guards scanned
+	scanned bool
+
+	mu    sync.RWMutex
+	files map[string]string
+}
+
+func NewLibrary(dir string) *Library {
+	return &Library{dir: dir}
+}
+
+func (l *Library) Scan(ctx context.Context) []models.Track {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+	return l.scan(ctx)
+}
+
+func (l *Library) scan(ctx context.Context) []models.Track {
+	files := make(map[string]string)
+	var tracks []models.Track
+
+	walkErr := filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil || d.IsDir() || !audioExts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		tags, err := readTags(path)
+		if err != nil || tags.Artist == "" || tags.Title == "" {
+			return nil
+		}
+
+		key := foldName(tags.Artist) + "|" + foldName(tags.Title)
+		if _, ok := files[key]; ok {
+			if tags.Lyrics != "" {
+				files[key] = path
+			}
+			return nil
+		}
+		files[key] = path
+
+		tracks = append(tracks, models.Track{
+			Artist:    tags.Artist,
+			Title:     tags.Title,
+			PlayCount: 0,
+		})
+		return nil
+	})
+	if walkErr != nil {
+		return nil
+	}
+
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].Artist != tracks[j].Artist {
+			return tracks[i].Artist < tracks[j].Artist
+		}
+		return tracks[i].Title < tracks[j].Title
+	})
+
+	l.mu.Lock()
+	l.files = files
+	l.mu.Unlock()
+	l.scanned = true
+
+	log.Printf("[library] %s: %d tagged tracks", l.dir, len(tracks))
+	return tracks
+}
+
+func (l *Library) Tracks(ctx context.Context, maxTracks int) []models.Track {
+	if l == nil || l.dir == "" {
+		return nil
+	}
+	tracks := l.Scan(ctx)
+	if len(tracks) > maxTracks {
+		tracks = tracks[:maxTracks]
+	}
+	return tracks
+}
+
+func (l *Library) Find(ctx context.Context, artist, title string) (string, bool) {
+	if l == nil || l.dir == "" {
+		return "", false
+	}
+
+	// Concurrent fetch workers wait for the first walk instead of each
+	// starting their own; a cancelled walk leaves scanned unset for a retry.
+	l.scanMu.Lock()
+	if !l.scanned {
+		l.scan(ctx)
+	}
+	l.scanMu.Unlock()
+
+	l.mu.RLock()
+	path, ok := l.files[foldName(artist)+"|"+foldName(title)]
+	l.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	tags, err := readTags(path)
+	if err != nil || tags.Lyrics == "" {
+		return "", false
+	}
+	return strings.TrimSpace(stripLRC(tags.Lyrics)), true
+}
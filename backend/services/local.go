@@ -0,0 +1,183 @@
+package services
+
+import (
+	"encoding/json"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const localMatchThreshold = 0.85
+
+var lrcTagRe = regexp.MustCompile(`\[[^\]]*\]|<\d+:\d+(?:[.:]\d+)?>`)
+
+type LocalLyrics struct {
+	dir     string
+	once    sync.Once
+	entries []localEntry
+	exact   map[string]string
+}
+
+type localEntry struct {
+	artist string
+	title  string
+	path   string
+}
+
+type localIndexItem struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	File   string `json:"file"`
+}
+
+func NewLocalLyrics(dir string) *LocalLyrics {
+	return &LocalLyrics{dir: dir}
+}
+
+func (l *LocalLyrics) load() {
+	l.exact = make(map[string]string)
+
+	if data, err := os.ReadFile(filepath.Join(l.dir, "index.json")); err == nil {
+		var items []localIndexItem
+		if err := json.Unmarshal(data, &items); err != nil {
+			log.Printf("[local] could not parse index.json: %v", err)
+		}
+		for _, it := range items {
+			path := it.File
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(l.dir, path)
+			}
+			l.add(it.Artist, it.Title, path)
+		}
+	}
+
+	filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".txt" && ext != ".lrc" {
+			return nil
+		}
+
+		title := strings.TrimSuffix(d.Name(), filepath.Ext(d.Name()))
+		artist := filepath.Base(filepath.Dir(path))
+
+		if rel, _ := filepath.Rel(l.dir, path); !strings.Contains(rel, string(filepath.Separator)) {
+			parts := strings.SplitN(title, " - ", 2)
+			if len(parts) != 2 {
+				return nil
+			}
+			artist, title = parts[0], parts[1]
+		}
+
+		l.add(artist, title, path)
+		return nil
+	})
+
+	log.Printf("[local] indexed %d lyric files in %s", len(l.entries), l.dir)
+}
+
+func (l *LocalLyrics) add(artist, title, path string) {
+	e := localEntry{artist: foldName(artist), title: foldName(title), path: path}
+	if e.artist == "" || e.title == "" {
+		return
+	}
+	key := e.artist + "|" + e.title
+	if _, ok := l.exact[key]; ok {
+		return
+	}
+	l.exact[key] = path
+	l.entries = append(l.entries, e)
+}
+
+func (l *LocalLyrics) Find(artist, title string) (string, bool) {
+	if l == nil || l.dir == "" {
+		return "", false
+	}
+	l.once.Do(l.load)
+
+	a, t := foldName(artist), foldName(title)
+
+	path, ok := l.exact[a+"|"+t]
+	if !ok {
+		best := 0.0
+		for _, e := range l.entries {
+			as := similarity(a, e.artist)
+			if as < localMatchThreshold {
+				continue
+			}
+			score := (as + similarity(t, e.title)) / 2
+			if score > best {
+				best, path = score, e.path
+			}
+		}
+		if best < localMatchThreshold {
+			return "", false
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("[local] could not read %s: %v", path, err)
+		return "", false
+	}
+
+	text := string(data)
+	if strings.EqualFold(filepath.Ext(path), ".lrc") {
+		text = stripLRC(text)
+	}
+	text = strings.TrimSpace(text)
+	return text, text != ""
+}
+
+func stripLRC(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(lrcTagRe.ReplaceAllString(line, ""))
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func foldName(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+type lookupStatus int
+
+const (
+	lookupNotFound lookupStatus = iota
+	lookupFound
+	lookupInstrumental
+	lookupTransient
+)
+
+type lookupResult struct {
+	Status     lookupStatus
+	Lyrics     string
+	Synced     string
+	Duration   float64
+	Confidence float64
+	Err        error
+}
+
+const (
+	lookupAttempts = 3
+	lookupBackoff  = 500 * time.Millisecond
+)
+
+var (
+	errNotFound = errors.New("not found")
+	errRejected = errors.New("request rejected")
+)
+
+type transientError struct {
+	err       error
+	retryable bool
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+func lookupFailed(err error) lookupResult {
+	if errors.Is(err, errNotFound) {
+		return lookupResult{Status: lookupNotFound}
+	}
+	if errors.Is(err, errRejected) {
+		return lookupResult{Status: lookupNotFound, Err: err}
+	}
+	return lookupResult{Status: lookupTransient, Err: err}
+}
+
+func (s *Lyrics) getJSON(req *http.Request, v interface{}) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return &transientError{err: err, retryable: req.Context().Err() == nil}
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &transientError{err: err, retryable: true}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return &transientError{err: fmt.Errorf("decode: %w", err), retryable: true}
+	}
+	return nil
+}
+
+func checkStatus(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500:
+		return &transientError{err: fmt.Errorf("HTTP %d", resp.StatusCode), retryable: true}
+	case resp.StatusCode >= 400:
+		// 401/403 and other client errors will not change on retry.
+		return fmt.Errorf("%s: HTTP %d: %w", resp.Request.URL.Host, resp.StatusCode, errRejected)
+	default:
+		return &transientError{err: fmt.Errorf("HTTP %d", resp.StatusCode)}
+	}
+}
+
+func withRetry(ctx context.Context, name string, fn func() lookupResult) lookupResult {
+	var r lookupResult
+	for attempt := 1; attempt <= lookupAttempts; attempt++ {
+		r = fn()
+		if r.Status != lookupTransient {
+			return r
+		}
+
+		var te *transientError
+		if !errors.As(r.Err, &te) || !te.retryable || attempt == lookupAttempts {
+			break
+		}
+
+		wait := lookupBackoff << (attempt - 1)
+		wait += time.Duration(rand.Int63n(int64(wait) / 2))
+		log.Printf("[lyrics] %s: %v, retrying in %s", name, r.Err, wait.Round(time.Millisecond))
+		if !sleepCtx(ctx, wait) {
+			return lookupFailed(ctx.Err())
+		}
+	}
+	return r
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"lastfm-lyrics/models"
+)
+
+const maxLineSeconds = 10.0
+
+var lrcTimeRe = regexp.MustCompile(`\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+
+type lrcLine struct {
+	At   float64
+	Text string
+}
+
+func parseLRC(text string) []lrcLine {
+	var lines []lrcLine
+
+	for _, raw := range strings.Split(text, "\n") {
+		stamps := lrcTimeRe.FindAllStringSubmatch(raw, -1)
+		if len(stamps) == 0 {
+			continue
+		}
+		body := strings.TrimSpace(lrcTagRe.ReplaceAllString(raw, ""))
+
+		for _, m := range stamps {
+			mins, _ := strconv.Atoi(m[1])
+			secs, _ := strconv.Atoi(m[2])
+			at := float64(mins*60 + secs)
+			if m[3] != "" {
+				frac, _ := strconv.Atoi(m[3])
+				at += float64(frac) / math.Pow(10, float64(len(m[3])))
+			}
+			lines = append(lines, lrcLine{At: at, Text: body})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].At < lines[j].At })
+	return lines
+}
+
+func vocalStats(synced string, duration float64) *models.TrackStats {
+	lines := parseLRC(synced)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	end := duration
+	if last := lines[len(lines)-1].At; end <= last {
+		end = last + maxLineSeconds
+	}
+
+	stats := &models.TrackStats{Synced: true, Duration: round2(duration)}
+	first := -1.0
+	vocal := 0.0
+	words := 0
+
+	for i, l := range lines {
+		if l.Text == "" {
+			continue
+		}
+		if first < 0 {
+			first = l.At
+		}
+
+		next := end
+		if i+1 < len(lines) {
+			next = lines[i+1].At
+		}
+		vocal += math.Min(next-l.At, maxLineSeconds)
+		words += len(strings.Fields(l.Text))
+	}
+
+	if first < 0 {
+		return stats
+	}
+
+	stats.FirstVocal = round2(first)
+	if vocal > 0 {
+		stats.WordsPerMinute = round2(float64(words) / (vocal / 60))
+	}
+	if duration > 0 {
+		stats.VocalCoverage = round2(math.Min(vocal/duration, 1))
+	}
+	return stats
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type lrclibResult struct {
+	ArtistName   string  `json:"artistName"`
+	TrackName    string  `json:"trackName"`
+	PlainLyrics  string  `json:"plainLyrics"`
+	SyncedLyrics string  `json:"syncedLyrics"`
+	Duration     float64 `json:"duration"`
+	Instrumental bool    `json:"instrumental"`
+}
+
+func (r *lrclibResult) usable() bool {
+	return r.PlainLyrics != "" || r.Instrumental
+}
+
+func (r *lrclibResult) lookup(confidence float64) lookupResult {
+	if r.Instrumental && r.PlainLyrics == "" {
+		return lookupResult{Status: lookupInstrumental, Duration: r.Duration, Confidence: confidence}
+	}
+	return lookupResult{
+		Status:     lookupFound,
+		Lyrics:     r.PlainLyrics,
+		Synced:     r.SyncedLyrics,
+		Duration:   r.Duration,
+		Confidence: confidence,
+	}
+}
+
+func (s *Lyrics) tryLrclib(ctx context.Context, artist, title string, duration float64) lookupResult {
+	if duration > 0 {
+		params := url.Values{
+			"artist_name": {artist},
+			"track_name":  {title},
+			"duration":    {strconv.Itoa(int(math.Round(duration)))},
+		}
+
+		var exact lrclibResult
+		err := s.lrclibRequest(ctx, "get", params, &exact)
+		switch {
+		case err == nil && exact.usable():
+			score := matchScore(artist, title, duration, exact.ArtistName, exact.TrackName, exact.Duration)
+			if score >= minMatchConfidence {
+				return exact.lookup(score)
+			}
+		case err != nil && err != errNotFound:
+			return lookupFailed(err)
+		}
+	}
+
+	params := url.Values{
+		"artist_name": {artist},
+		"track_name":  {title},
+	}
+
+	var results []lrclibResult
+	if err := s.lrclibRequest(ctx, "search", params, &results); err != nil {
+		return lookupFailed(err)
+	}
+
+	best, bestScore := -1, 0.0
+	for i, r := range results {
+		if !r.usable() {
+			continue
+		}
+		score := matchScore(artist, title, duration, r.ArtistName, r.TrackName, r.Duration)
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 {
+		return lookupResult{Status: lookupNotFound}
+	}
+	if bestScore < minMatchConfidence {
+		log.Printf("[lyrics] lrclib best match rejected (%.2f): %s — %s → %s — %s",
+			bestScore, artist, title, results[best].ArtistName, results[best].TrackName)
+		return lookupResult{Status: lookupNotFound}
+	}
+	return results[best].lookup(bestScore)
+}
+
+func (s *Lyrics) lrclibRequest(ctx context.Context, endpoint string, params url.Values, v interface{}) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET",
+		"https://lrclib.net/api/"+endpoint+"?"+params.Encode(), nil)
+	req.Header.Set("User-Agent", "LastFmLyricsAnalyzer/1.0")
+
+	return s.getJSON(req, v)
+}
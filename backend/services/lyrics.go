@@ -1,48 +1,87 @@
 package services
 
 import (
-	"encoding/json"
-	"io"
+	"context"
 	"log"
+	"math"
 	"net/http"
-	"net/url"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"lastfm-lyrics/cache"
 	"lastfm-lyrics/models"
-
-	"golang.org/x/net/html"
 )
 
 type Lyrics struct {
-	geniusToken string
-	cache       *cache.LyricsCache
-	client      *http.Client
+	geniusToken  string
+	offline      bool
+	hedgeDelay   time.Duration
+	providerList []*provider
+	local        *LocalLyrics
+	library      *Library
+	cache        *cache.LyricsCache
+	client       *http.Client
+	mb           *MusicBrainz
+	aliasMu      sync.Mutex
+	aliasCalls   map[string]chan struct{}
+}
+
+type LyricsOptions struct {
+	GeniusToken   string
+	Offline       bool
+	HedgeDelay    time.Duration
+	LrclibWorkers int
+	GeniusWorkers int
 }
 
-func NewLyrics(geniusToken string, c *cache.LyricsCache) *Lyrics {
-	return &Lyrics{
-		geniusToken: geniusToken,
+func NewLyrics(opts LyricsOptions, local *LocalLyrics, library *Library, c *cache.LyricsCache) *Lyrics {
+	s := &Lyrics{
+		geniusToken: opts.GeniusToken,
+		offline:     opts.Offline,
+		hedgeDelay:  opts.HedgeDelay,
+		local:       local,
+		library:     library,
 		cache:       c,
 		client:      &http.Client{Timeout: 10 * time.Second},
+		mb:          NewMusicBrainz(),
+		aliasCalls:  make(map[string]chan struct{}),
 	}
+	s.providerList = []*provider{
+		newProvider("lrclib", opts.LrclibWorkers, s.tryLrclib),
+		newProvider("genius", opts.GeniusWorkers, func(ctx context.Context, artist, title string, _ float64) lookupResult {
+			return s.tryGenius(ctx, artist, title)
+		}),
+	}
+	return s
+}
+
+type FetchResult struct {
+	Lyrics       map[string]string
+	Stats        []models.TrackStats
+	Missing      []models.Track
+	Instrumental int
 }
 
 func (s *Lyrics) FetchAll(
+	ctx context.Context,
 	tracks []models.Track,
 	workers int,
 	progressFn func(processed, found int, current string),
-) map[string]string {
+) *FetchResult {
 
 	type job struct {
 		track models.Track
 	}
 	type result struct {
-		key    string
-		lyrics string
-		found  bool
+		track models.Track
+		key   string
+		entry cache.Entry
 	}
 
 	jobs := make(chan job, len(tracks))
@@ -51,9 +90,12 @@ func (s *Lyrics) FetchAll(
 	for w := 0; w < workers; w++ {
 		go func() {
 			for j := range jobs {
-				lyrics, found, _ := s.fetchOne(j.track.Artist, j.track.Title)
+				var entry cache.Entry
+				if ctx.Err() == nil {
+					entry = s.fetchOne(ctx, j.track)
+				}
 				key := j.track.Artist + " — " + j.track.Title
-				results <- result{key: key, lyrics: lyrics, found: found}
+				results <- result{track: j.track, key: key, entry: entry}
 			}
 		}()
 	}
@@ -63,205 +105,281 @@ func (s *Lyrics) FetchAll(
 	}
 	close(jobs)
 
-	lyricsMap := make(map[string]string)
+	res := &FetchResult{Lyrics: make(map[string]string)}
 	processed := 0
 	found := 0
 
 	for range tracks {
 		r := <-results
 		processed++
-		if r.found {
-			lyricsMap[r.key] = r.lyrics
+		if r.entry.Found {
+			res.Lyrics[r.key] = r.entry.Lyrics
 			found++
+
+			st := models.TrackStats{Duration: round2(r.entry.Duration)}
+			if r.entry.Synced != "" {
+				if vs := vocalStats(r.entry.Synced, r.entry.Duration); vs != nil {
+					st = *vs
+				}
+			}
+			st.Track = r.key
+			st.Source = r.entry.Source
+			st.Confidence = round2(r.entry.Confidence)
+			res.Stats = append(res.Stats, st)
+		} else if r.entry.Instrumental {
+			res.Instrumental++
+		} else {
+			res.Missing = append(res.Missing, r.track)
 		}
 		if progressFn != nil {
 			progressFn(processed, found, r.key)
 		}
 	}
 
-	return lyricsMap
-}
+	sort.Slice(res.Stats, func(i, j int) bool { return res.Stats[i].Track < res.Stats[j].Track })
+	sort.SliceStable(res.Missing, func(i, j int) bool {
+		return res.Missing[i].PlayCount > res.Missing[j].PlayCount
+	})
 
-func (s *Lyrics) fetchOne(artist, title string) (string, bool, string) {
-	cleaned := cleanTitle(title)
-
-	if entry, ok := s.cache.Get(artist, cleaned); ok {
-		return entry.Lyrics, entry.Found, "cache"
-	}
+	return res
+}
 
-	if lyrics, ok := s.tryLrclib(artist, cleaned); ok {
-		if isReasonableLyrics(lyrics) {
-			log.Printf("[lyrics] lrclib: %s — %s", artist, cleaned)
-			s.cache.Set(artist, cleaned, lyrics, "lrclib", true)
-			return lyrics, true, "lrclib"
+func (s *Lyrics) Recheck(ctx context.Context, tracks []models.Track) int {
+	found := 0
+	for _, t := range tracks {
+		cleaned := cleanTitle(t.Title)
+		if entry, ok := s.cache.Get(t.Artist, cleaned); ok && (entry.Found || entry.Instrumental) {
+			continue
 		}
-		log.Printf("[lyrics] lrclib text too long, skipping: %s — %s", artist, cleaned)
-	}
 
-	if s.geniusToken != "" {
-		if lyrics, ok := s.tryGenius(artist, cleaned); ok {
-			if isReasonableLyrics(lyrics) {
-				log.Printf("[lyrics] genius: %s — %s", artist, cleaned)
-				s.cache.Set(artist, cleaned, lyrics, "genius", true)
-				return lyrics, true, "genius"
-			}
-			log.Printf("[lyrics] genius text too long, skipping: %s — %s", artist, cleaned)
+		if s.fetchRemote(ctx, t).Found {
+			found++
+		}
+		if !sleepCtx(ctx, 500*time.Millisecond) {
+			break
 		}
 	}
-
-	log.Printf("[lyrics] not found: %s — %s", artist, cleaned)
-	s.cache.Set(artist, cleaned, "", "none", false)
-	return "", false, ""
-}
-
-func isReasonableLyrics(text string) bool {
-	wordCount := len(strings.Fields(text))
-	return wordCount >= 10 && wordCount <= 3000
+	return found
 }
 
-type lrclibResult struct {
-	PlainLyrics string `json:"plainLyrics"`
-}
+func (s *Lyrics) fetchOne(ctx context.Context, track models.Track) cache.Entry {
+	artist, title := track.Artist, track.Title
+	cleaned := cleanTitle(title)
 
-func (s *Lyrics) tryLrclib(artist, title string) (string, bool) {
-	params := url.Values{
-		"artist_name": {artist},
-		"track_name":  {title},
+	cached, inCache := s.cache.Get(artist, cleaned)
+	if inCache && cached.Source == cache.SourceManual {
+		return *cached
 	}
 
-	req, _ := http.NewRequest("GET",
-		"https://lrclib.net/api/search?"+params.Encode(), nil)
-	req.Header.Set("User-Agent", "LastFmLyricsAnalyzer/1.0")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return "", false
+	if lyrics, ok := s.local.Find(artist, title); ok {
+		return cache.Entry{Lyrics: cleanLyrics(lyrics, cleaned, "local"), Source: "local", Confidence: 1, Found: true}
+	}
+	if lyrics, ok := s.local.Find(artist, cleaned); ok {
+		return cache.Entry{Lyrics: cleanLyrics(lyrics, cleaned, "local"), Source: "local", Confidence: 1, Found: true}
+	}
+	if lyrics, ok := s.library.Find(ctx, artist, title); ok {
+		return cache.Entry{Lyrics: cleanLyrics(lyrics, cleaned, "embedded"), Source: "embedded", Confidence: 1, Found: true}
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	if track.Instrumental {
+		return cache.Entry{Source: "musicbrainz", Instrumental: true}
+	}
+	if reInstrumental.MatchString(title) {
+		return cache.Entry{Source: "title", Instrumental: true}
+	}
 
-	var results []lrclibResult
-	if err := json.Unmarshal(body, &results); err != nil {
-		return "", false
+	if inCache {
+		return *cached
 	}
 
-	for _, r := range results {
-		if r.PlainLyrics != "" {
-			return r.PlainLyrics, true
-		}
+	if s.offline {
+		return cache.Entry{}
 	}
-	return "", false
-}
 
-type geniusSearch struct {
-	Response struct {
-		Hits []struct {
-			Result struct {
-				URL string `json:"url"`
-			} `json:"result"`
-		} `json:"hits"`
-	} `json:"response"`
+	return s.fetchRemote(ctx, track)
 }
 
-func (s *Lyrics) tryGenius(artist, title string) (string, bool) {
-
-	query := artist + " " + title
-	params := url.Values{"q": {query}}
-
-	req, _ := http.NewRequest("GET",
-		"https://api.genius.com/search?"+params.Encode(), nil)
-	req.Header.Set("Authorization", "Bearer "+s.geniusToken)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return "", false
+func (s *Lyrics) fetchRemote(ctx context.Context, track models.Track) cache.Entry {
+	artist := track.Artist
+	cleaned := cleanTitle(track.Title)
+
+	r, failed := s.race(ctx, cleanArtist(artist), cleaned, track.Duration)
+	queried := artist
+	if r.Status != lookupFound && r.Status != lookupInstrumental && ctx.Err() == nil {
+		for _, alias := range s.aliases(ctx, artist) {
+			ar, aliasFailed := s.race(ctx, cleanArtist(alias), cleaned, track.Duration)
+			failed = failed || aliasFailed
+			if ar.Status == lookupFound || ar.Status == lookupInstrumental {
+				r, queried = ar, alias
+				break
+			}
+		}
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 
-	var search geniusSearch
-	if err := json.Unmarshal(body, &search); err != nil {
-		return "", false
+	via := ""
+	if queried != artist {
+		via = " (as " + queried + ")"
 	}
 
-	if len(search.Response.Hits) == 0 {
-		return "", false
+	switch r.Status {
+	case lookupFound:
+		log.Printf("[lyrics] %s (%.2f): %s — %s%s", r.name, r.Confidence, artist, cleaned, via)
+		return s.store(artist, cleaned, r.name, r.lookupResult)
+	case lookupInstrumental:
+		log.Printf("[lyrics] %s instrumental: %s — %s%s", r.name, artist, cleaned, via)
+		return s.store(artist, cleaned, r.name, r.lookupResult)
 	}
 
-	songURL := search.Response.Hits[0].Result.URL
+	if failed {
+		log.Printf("[lyrics] lookup incomplete, not caching: %s — %s", artist, cleaned)
+		return cache.Entry{}
+	}
 
-	time.Sleep(300 * time.Millisecond)
+	log.Printf("[lyrics] not found: %s — %s", artist, cleaned)
+	s.cache.Set(artist, cleaned, cache.Entry{Source: "none"})
+	return cache.Entry{}
+}
 
-	pageReq, _ := http.NewRequest("GET", songURL, nil)
-	pageReq.Header.Set("User-Agent",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+func (s *Lyrics) aliases(ctx context.Context, artist string) []string {
+	if !s.offline && !s.cache.AliasesSeeded(artist) {
+		s.seedAliases(ctx, artist)
+	}
 
-	pageResp, err := s.client.Do(pageReq)
+	stored, err := s.cache.Aliases(artist)
 	if err != nil {
-		return "", false
+		log.Printf("[cache] alias read error: %v", err)
+		return nil
 	}
-	defer pageResp.Body.Close()
 
-	lyrics := parseGeniusHTML(pageResp.Body)
-	if lyrics == "" {
-		return "", false
+	names := make([]string, 0, len(stored))
+	for _, a := range stored {
+		if !strings.EqualFold(a.Alias, artist) {
+			names = append(names, a.Alias)
+		}
 	}
-	return lyrics, true
+	return names
 }
 
-func parseGeniusHTML(r io.Reader) string {
-	doc, err := html.Parse(r)
+// seedAliases looks the artist up on MusicBrainz once. Workers asking for
+// the same artist meanwhile wait for that lookup; other artists don't.
+func (s *Lyrics) seedAliases(ctx context.Context, artist string) {
+	key := strings.ToLower(strings.TrimSpace(artist))
+
+	s.aliasMu.Lock()
+	if done, ok := s.aliasCalls[key]; ok {
+		s.aliasMu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		return
+	}
+	done := make(chan struct{})
+	s.aliasCalls[key] = done
+	s.aliasMu.Unlock()
+
+	defer func() {
+		s.aliasMu.Lock()
+		delete(s.aliasCalls, key)
+		s.aliasMu.Unlock()
+		close(done)
+	}()
+
+	if s.cache.AliasesSeeded(artist) {
+		return
+	}
+	names, err := s.mb.ArtistAliases(ctx, artist)
 	if err != nil {
-		return ""
+		log.Printf("[lyrics] alias lookup for %s failed: %v", artist, err)
+	} else if err := s.cache.SeedAliases(artist, names); err != nil {
+		log.Printf("[cache] alias write error: %v", err)
+	}
+}
+
+func (s *Lyrics) store(artist, title, source string, r lookupResult) cache.Entry {
+	entry := cache.Entry{
+		Lyrics:       r.Lyrics,
+		Synced:       r.Synced,
+		Duration:     r.Duration,
+		Source:       source,
+		Confidence:   r.Confidence,
+		Found:        r.Status == lookupFound,
+		Instrumental: r.Status == lookupInstrumental,
 	}
+	s.cache.Set(artist, title, entry)
+	return entry
+}
 
-	var sb strings.Builder
+func isReasonableLyrics(text string) bool {
+	wordCount := len(strings.Fields(text))
+	return wordCount >= 10 && wordCount <= 3000
+}
 
-	var find func(*html.Node)
-	find = func(n *html.Node) {
+const (
+	minMatchConfidence = 0.75
+	minTitleSimilarity = 0.8
+	minContainedArtist = 3
+	maxDurationDelta   = 10.0
+)
 
-		if n.Type == html.ElementNode && n.Data == "div" {
-			for _, a := range n.Attr {
-				if a.Key == "data-lyrics-container" && a.Val == "true" {
-					getText(n, &sb)
-					sb.WriteString("\n")
-					return
-				}
-			}
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			find(c)
-		}
+// artistSimilarity compares two artist names as written. A name that
+// appears in the other as whole words ("Queen" in "Queen & David Bowie")
+// counts as a near match; a bare substring ("Air" in "Fairport") does not.
+func artistSimilarity(a, b string) float64 {
+	sim := similarity(foldName(a), foldName(b))
+
+	ta, tb := nameTokens(a), nameTokens(b)
+	if len(ta) > len(tb) {
+		ta, tb = tb, ta
+	}
+	if utf8.RuneCountInString(strings.Join(ta, "")) >= minContainedArtist && containsTokens(tb, ta) {
+		sim = math.Max(sim, 0.9)
 	}
+	return sim
+}
 
-	find(doc)
-	return strings.TrimSpace(sb.String())
+func nameTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
 }
 
-func getText(n *html.Node, sb *strings.Builder) {
-	if n.Type == html.TextNode {
-		sb.WriteString(n.Data)
+func containsTokens(haystack, needle []string) bool {
+	if len(needle) == 0 {
+		return false
 	}
-	if n.Type == html.ElementNode && n.Data == "br" {
-		sb.WriteString("\n")
-	}
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		getText(c, sb)
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if slices.Equal(haystack[i:i+len(needle)], needle) {
+			return true
+		}
 	}
+	return false
 }
 
-var (
-	reParens = regexp.MustCompile(`\s*[\(\[].*?[\)\]]\s*`)
+func matchScore(artist, title string, duration float64, gotArtist, gotTitle string, gotDuration float64) float64 {
+	artistSim := artistSimilarity(artist, gotArtist)
+	titleSim := max(
+		similarity(foldName(title), foldName(gotTitle)),
+		similarity(foldName(title), foldName(cleanTitle(gotTitle))),
+	)
+	// A strong artist and duration match must not carry a different song.
+	if titleSim < minTitleSimilarity {
+		return 0
+	}
 
-	reSuffix = regexp.MustCompile(
-		`(?i)\s*-\s*(remaster|live|demo|remix|deluxe|bonus|edit|version|` +
-			`mix|single|acoustic|instrumental|radio|extended|original).*`)
-)
+	if duration <= 0 || gotDuration <= 0 {
+		return (artistSim + titleSim) / 2
+	}
 
-func cleanTitle(title string) string {
-	title = reParens.ReplaceAllString(title, " ")
-	title = reSuffix.ReplaceAllString(title, "")
-	return strings.TrimSpace(title)
+	delta := math.Abs(duration - gotDuration)
+	durationSim := math.Max(0, 1-delta/maxDurationDelta)
+	return 0.4*artistSim + 0.4*titleSim + 0.2*durationSim
 }
+
+// reInstrumental matches an instrumental marker in brackets, as in
+// "(Instrumental)" or "[Karaoke / Inst.]", or after a dash at the end of the
+// title, as in "- Instrumental Version". A title that merely starts with the
+// word, such as "Instrumental Asylum", is a regular song.
+var reInstrumental = regexp.MustCompile(`(?i)` +
+	`[(\[](?:[^)\]]*[\s\-–—/,])?(?:instrumental|instr?\.?|инструментал)(?:[\s\-–—/,.][^)\]]*)?[)\]]` +
+	`|\s[-–—]\s*(?:instrumental|instr?\.?|инструментал)(?:[\s\-–—/,.(\[].*)?$`)
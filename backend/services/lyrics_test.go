@@ -0,0 +1,29 @@
+package services
+
+import "testing"
+
+func TestInstrumentalTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"Night Run (Instrumental)", true},
+		{"Night Run [Instrumental Version]", true},
+		{"Night Run (Karaoke / Inst.)", true},
+		{"Night Run (inst)", true},
+		{"Night Run - Instrumental", true},
+		{"Night Run – Instrumental Mix", true},
+		{"Белая ночь (инструментал)", true},
+		{"Instrumental Asylum", false},
+		{"Instrumental", false},
+		{"Inst. of Love", false},
+		{"Institution (Live)", false},
+		{"Night Run (Instrumentally Yours)", false},
+		{"Night Run - Institution", false},
+	}
+	for _, tt := range tests {
+		if got := reInstrumental.MatchString(tt.title); got != tt.want {
+			t.Errorf("reInstrumental.MatchString(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
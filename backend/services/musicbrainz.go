@@ -1,12 +1,15 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"slices"
+	"sync"
 	"time"
 
 	"lastfm-lyrics/models"
@@ -22,8 +25,28 @@ func NewMusicBrainz() *MusicBrainz {
 	}
 }
 
-func (mb *MusicBrainz) mbRequest(url string) ([]byte, error) {
-	req, _ := http.NewRequest("GET", url, nil)
+var (
+	mbMu   sync.Mutex
+	mbLast time.Time
+)
+
+func mbThrottle(ctx context.Context) error {
+	mbMu.Lock()
+	defer mbMu.Unlock()
+
+	if wait := time.Until(mbLast.Add(time.Second)); wait > 0 && !sleepCtx(ctx, wait) {
+		return ctx.Err()
+	}
+	mbLast = time.Now()
+	return nil
+}
+
+func (mb *MusicBrainz) mbRequest(ctx context.Context, url string) ([]byte, error) {
+	if err := mbThrottle(ctx); err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	req.Header.Set("User-Agent", "LastFmLyricsAnalyzer/1.0 (contact@example.com)")
 	req.Header.Set("Accept", "application/json")
 
@@ -37,8 +60,10 @@ func (mb *MusicBrainz) mbRequest(url string) ([]byte, error) {
 
 	if resp.StatusCode == 503 {
 
-		time.Sleep(2 * time.Second)
-		return mb.mbRequest(url)
+		if !sleepCtx(ctx, 2*time.Second) {
+			return nil, ctx.Err()
+		}
+		return mb.mbRequest(ctx, url)
 	}
 
 	if resp.StatusCode != 200 {
@@ -48,14 +73,14 @@ func (mb *MusicBrainz) mbRequest(url string) ([]byte, error) {
 	return body, nil
 }
 
-func (mb *MusicBrainz) FindArtist(name string) (string, string, error) {
+func (mb *MusicBrainz) FindArtist(ctx context.Context, name string) (string, string, error) {
 	params := url.Values{
 		"query": {name},
 		"limit": {"5"},
 		"fmt":   {"json"},
 	}
 
-	body, err := mb.mbRequest("https://musicbrainz.org/ws/2/artist/?" + params.Encode())
+	body, err := mb.mbRequest(ctx, "https://musicbrainz.org/ws/2/artist/?"+params.Encode())
 	if err != nil {
 		return "", "", err
 	}
@@ -80,8 +105,94 @@ func (mb *MusicBrainz) FindArtist(name string) (string, string, error) {
 	return artist.ID, artist.Name, nil
 }
 
-func (mb *MusicBrainz) GetDiscography(artistID, artistName string, maxTracks int) ([]models.Track, error) {
-	releaseGroups, err := mb.getReleaseGroups(artistID)
+const maxArtistAliases = 5
+
+func (mb *MusicBrainz) ArtistAliases(ctx context.Context, name string) ([]string, error) {
+	params := url.Values{
+		"query": {name},
+		"limit": {"5"},
+		"fmt":   {"json"},
+	}
+
+	body, err := mb.mbRequest(ctx, "https://musicbrainz.org/ws/2/artist/?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	type mbAlias struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Primary bool   `json:"primary"`
+	}
+	var result struct {
+		Artists []struct {
+			Name    string    `json:"name"`
+			Score   int       `json:"score"`
+			Aliases []mbAlias `json:"aliases"`
+		} `json:"artists"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	key := foldName(name)
+	match := -1
+	for i, a := range result.Artists {
+		if foldName(a.Name) == key || slices.ContainsFunc(a.Aliases, func(al mbAlias) bool {
+			return foldName(al.Name) == key
+		}) {
+			match = i
+			break
+		}
+	}
+	if match < 0 && len(result.Artists) > 0 && result.Artists[0].Score >= 95 &&
+		artistSimilarity(name, result.Artists[0].Name) >= 0.9 {
+		match = 0
+	}
+	if match < 0 {
+		return nil, nil
+	}
+
+	artist := result.Artists[match]
+	slices.SortStableFunc(artist.Aliases, func(a, b mbAlias) int {
+		switch {
+		case a.Primary == b.Primary:
+			return 0
+		case a.Primary:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	names := []string{artist.Name}
+	for _, a := range artist.Aliases {
+		if a.Type != "Search hint" {
+			names = append(names, a.Name)
+		}
+	}
+
+	seen := map[string]bool{key: true}
+	var aliases []string
+	for _, n := range names {
+		k := foldName(n)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		aliases = append(aliases, n)
+		if len(aliases) == maxArtistAliases {
+			break
+		}
+	}
+
+	log.Printf("[musicbrainz] aliases for %s: %v", name, aliases)
+	return aliases, nil
+}
+
+func (mb *MusicBrainz) GetDiscography(ctx context.Context, artistID, artistName string, maxTracks int) ([]models.Track, error) {
+	releaseGroups, err := mb.getReleaseGroups(ctx, artistID)
 	if err != nil {
 		return nil, err
 	}
@@ -96,25 +207,32 @@ func (mb *MusicBrainz) GetDiscography(artistID, artistName string, maxTracks int
 			break
 		}
 
-		time.Sleep(1100 * time.Millisecond)
+		if !sleepCtx(ctx, 1100*time.Millisecond) {
+			return nil, ctx.Err()
+		}
 
-		rgTracks, err := mb.getTracksFromReleaseGroup(rg.ID)
+		rgTracks, err := mb.getTracksFromReleaseGroup(ctx, rg.ID)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			log.Printf("[musicbrainz] warning: %v", err)
 			continue
 		}
 
-		for _, title := range rgTracks {
-			key := normalizeTitle(title)
+		for _, t := range rgTracks {
+			key := normalizeTitle(t.Title)
 			if seen[key] {
 				continue
 			}
 			seen[key] = true
 
 			tracks = append(tracks, models.Track{
-				Artist:    artistName,
-				Title:     title,
-				PlayCount: 0,
+				Artist:       artistName,
+				Title:        t.Title,
+				PlayCount:    0,
+				Duration:     t.Duration,
+				Instrumental: t.Instrumental,
 			})
 
 			if len(tracks) >= maxTracks {
@@ -136,7 +254,7 @@ type releaseGroup struct {
 	Type  string `json:"primary-type"`
 }
 
-func (mb *MusicBrainz) getReleaseGroups(artistID string) ([]releaseGroup, error) {
+func (mb *MusicBrainz) getReleaseGroups(ctx context.Context, artistID string) ([]releaseGroup, error) {
 	var all []releaseGroup
 	offset := 0
 	limit := 100
@@ -149,7 +267,7 @@ func (mb *MusicBrainz) getReleaseGroups(artistID string) ([]releaseGroup, error)
 			"fmt":    {"json"},
 		}
 
-		body, err := mb.mbRequest("https://musicbrainz.org/ws/2/release-group?" + params.Encode())
+		body, err := mb.mbRequest(ctx, "https://musicbrainz.org/ws/2/release-group?"+params.Encode())
 		if err != nil {
 			return nil, err
 		}
@@ -170,22 +288,24 @@ func (mb *MusicBrainz) getReleaseGroups(artistID string) ([]releaseGroup, error)
 		}
 
 		offset += limit
-		time.Sleep(1100 * time.Millisecond)
+		if !sleepCtx(ctx, 1100*time.Millisecond) {
+			return nil, ctx.Err()
+		}
 	}
 
 	return all, nil
 }
 
-func (mb *MusicBrainz) getTracksFromReleaseGroup(rgID string) ([]string, error) {
+func (mb *MusicBrainz) getTracksFromReleaseGroup(ctx context.Context, rgID string) ([]models.Track, error) {
 
 	params := url.Values{
 		"release-group": {rgID},
 		"limit":         {"1"},
-		"inc":           {"recordings"},
+		"inc":           {"recordings recording-level-rels work-rels"},
 		"fmt":           {"json"},
 	}
 
-	body, err := mb.mbRequest("https://musicbrainz.org/ws/2/release?" + params.Encode())
+	body, err := mb.mbRequest(ctx, "https://musicbrainz.org/ws/2/release?"+params.Encode())
 	if err != nil {
 		return nil, err
 	}
@@ -194,7 +314,14 @@ func (mb *MusicBrainz) getTracksFromReleaseGroup(rgID string) ([]string, error)
 		Releases []struct {
 			Media []struct {
 				Tracks []struct {
-					Title string `json:"title"`
+					Title     string `json:"title"`
+					Length    int    `json:"length"`
+					Recording struct {
+						Relations []struct {
+							Type       string   `json:"type"`
+							Attributes []string `json:"attributes"`
+						} `json:"relations"`
+					} `json:"recording"`
 				} `json:"tracks"`
 			} `json:"media"`
 		} `json:"releases"`
@@ -204,18 +331,28 @@ func (mb *MusicBrainz) getTracksFromReleaseGroup(rgID string) ([]string, error)
 		return nil, err
 	}
 
-	var titles []string
+	var tracks []models.Track
 	if len(result.Releases) > 0 {
 		for _, media := range result.Releases[0].Media {
 			for _, track := range media.Tracks {
 				if track.Title != "" {
-					titles = append(titles, track.Title)
+					instrumental := false
+					for _, rel := range track.Recording.Relations {
+						if rel.Type == "performance" && slices.Contains(rel.Attributes, "instrumental") {
+							instrumental = true
+						}
+					}
+					tracks = append(tracks, models.Track{
+						Title:        track.Title,
+						Duration:     float64(track.Length) / 1000,
+						Instrumental: instrumental,
+					})
 				}
 			}
 		}
 	}
 
-	return titles, nil
+	return tracks, nil
 }
 
 func normalizeTitle(s string) string {
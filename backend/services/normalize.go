@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"lastfm-lyrics/cache"
+	"lastfm-lyrics/models"
+)
+
+const (
+	scopeTitle  = "title"
+	scopeArtist = "artist"
+)
+
+type normRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+	Scope   string `json:"scope"`
+
+	re *regexp.Regexp
+}
+
+var (
+	titleRules  = defaultTitleRules()
+	artistRules []normRule
+)
+
+func defaultTitleRules() []normRule {
+	return []normRule{
+		{
+			Name:  "brackets",
+			re:    regexp.MustCompile(`\s*[\(\[].*?[\)\]]\s*`),
+			Scope: scopeTitle, Replace: " ",
+		},
+		{
+			Name: "suffix",
+			re: regexp.MustCompile(
+				`(?i)\s*-\s*(remaster|live|demo|remix|deluxe|bonus|edit|version|` +
+					`mix|single|acoustic|instrumental|radio|extended|original).*`),
+			Scope: scopeTitle,
+		},
+	}
+}
+
+func LoadNormalizationRules(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("[normalize] could not read %s: %v, using built-in rules", path, err)
+		return
+	}
+
+	var rules []normRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Printf("[normalize] could not parse %s: %v, using built-in rules", path, err)
+		return
+	}
+
+	var title, artist []normRule
+	for i, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			log.Printf("[normalize] skipping rule %d (%s): %v", i, r.Name, err)
+			continue
+		}
+		r.re = re
+		if r.Name == "" {
+			r.Name = r.Pattern
+		}
+
+		switch r.Scope {
+		case scopeTitle, "":
+			title = append(title, r)
+		case scopeArtist:
+			artist = append(artist, r)
+		case "both":
+			title = append(title, r)
+			artist = append(artist, r)
+		default:
+			log.Printf("[normalize] skipping rule %d (%s): unknown scope %q", i, r.Name, r.Scope)
+		}
+	}
+
+	titleRules, artistRules = title, artist
+	log.Printf("[normalize] loaded %d title and %d artist rules from %s", len(title), len(artist), path)
+}
+
+func applyRules(rules []normRule, s string, steps *[]models.NormalizeStep) string {
+	for _, r := range rules {
+		out := r.re.ReplaceAllString(s, r.Replace)
+		if steps != nil && out != s {
+			*steps = append(*steps, models.NormalizeStep{Rule: r.Name, Output: out})
+		}
+		s = out
+	}
+	return strings.TrimSpace(s)
+}
+
+// MigrateTitleKeys re-keys cache entries whose stored title no longer
+// survives cleanTitle, such as "song feat. x" or "song - 2011 remaster"
+// written before the rules file existed. A key the current rules leave
+// alone is kept even if the old rules folded other titles onto it, since
+// it is still the live key of the plain title. Entries whose new key is
+// already taken stay where they are.
+func MigrateTitleKeys(c *cache.LyricsCache) {
+	keys, err := c.TitleKeys()
+	if err != nil {
+		log.Printf("[cache] could not list keys for migration: %v", err)
+		return
+	}
+
+	moved, skipped := 0, 0
+	for _, k := range keys {
+		to := cleanTitle(k.Title)
+		if to == "" || to == k.Title {
+			continue
+		}
+		ok, err := c.Rekey(k.Artist, k.Title, to)
+		switch {
+		case err != nil:
+			log.Printf("[cache] re-key error for %s — %s: %v", k.Artist, k.Title, err)
+		case ok:
+			moved++
+		default:
+			skipped++
+		}
+	}
+	if moved > 0 || skipped > 0 {
+		log.Printf("[cache] title keys migrated: %d moved, %d left in place", moved, skipped)
+	}
+}
+
+func CleanTitle(title string) string {
+	return cleanTitle(title)
+}
+
+func cleanTitle(title string) string {
+	return applyRules(titleRules, title, nil)
+}
+
+func cleanArtist(artist string) string {
+	if cleaned := applyRules(artistRules, artist, nil); cleaned != "" {
+		return cleaned
+	}
+	return strings.TrimSpace(artist)
+}
+
+func ExplainNormalization(artist, title string) models.NormalizeResponse {
+	explain := func(rules []normRule, s string) models.Normalization {
+		n := models.Normalization{Input: s, Steps: []models.NormalizeStep{}}
+		n.Output = applyRules(rules, s, &n.Steps)
+		return n
+	}
+
+	return models.NormalizeResponse{
+		Artist: explain(artistRules, artist),
+		Title:  explain(titleRules, title),
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"lastfm-lyrics/models"
+)
+
+const (
+	maxPhraseLen = 3
+	maxPhrases   = 100
+)
+
+type phraseCounter struct {
+	counts map[string]int
+	tracks map[string]map[string]bool
+}
+
+func newPhraseCounter() *phraseCounter {
+	return &phraseCounter{
+		counts: make(map[string]int),
+		tracks: make(map[string]map[string]bool),
+	}
+}
+
+// add counts the n-grams of one line. Lines are passed separately so
+// phrases never span a line break.
+func (p *phraseCounter) add(track string, line []string, isStop func(string) bool) {
+	for n := 2; n <= maxPhraseLen; n++ {
+		for i := 0; i+n <= len(line); i++ {
+			gram := line[i : i+n]
+
+			allStop := true
+			for _, w := range gram {
+				if !isStop(w) {
+					allStop = false
+					break
+				}
+			}
+			if allStop {
+				continue
+			}
+
+			phrase := strings.Join(gram, " ")
+			p.counts[phrase]++
+			if p.tracks[phrase] == nil {
+				p.tracks[phrase] = make(map[string]bool)
+			}
+			p.tracks[phrase][track] = true
+		}
+	}
+}
+
+func (p *phraseCounter) top(minTracks, limit int) []models.PhraseCount {
+	var result []models.PhraseCount
+	for phrase, count := range p.counts {
+		if len(p.tracks[phrase]) < minTracks {
+			continue
+		}
+
+		tracks := make([]string, 0, len(p.tracks[phrase]))
+		for t := range p.tracks[phrase] {
+			tracks = append(tracks, t)
+		}
+		sort.Strings(tracks)
+
+		result = append(result, models.PhraseCount{
+			Phrase: phrase,
+			Words:  strings.Count(phrase, " ") + 1,
+			Count:  count,
+			Tracks: tracks,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if len(result[i].Tracks) != len(result[j].Tracks) {
+			return len(result[i].Tracks) > len(result[j].Tracks)
+		}
+		return result[i].Phrase < result[j].Phrase
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
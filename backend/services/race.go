@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const confidentMatch = 0.9
+
+type provider struct {
+	name  string
+	fetch func(ctx context.Context, artist, title string, duration float64) lookupResult
+	slots chan struct{}
+}
+
+type providerResult struct {
+	name string
+	lookupResult
+}
+
+func newProvider(name string, limit int, fetch func(context.Context, string, string, float64) lookupResult) *provider {
+	if limit < 1 {
+		limit = 1
+	}
+	return &provider{name: name, fetch: fetch, slots: make(chan struct{}, limit)}
+}
+
+func (p *provider) run(ctx context.Context, artist, title string, duration float64) lookupResult {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return lookupFailed(ctx.Err())
+	}
+	defer func() { <-p.slots }()
+
+	r := withRetry(ctx, p.name, func() lookupResult {
+		return p.fetch(ctx, artist, title, duration)
+	})
+	if r.Status == lookupFound {
+		r.Lyrics = cleanLyrics(r.Lyrics, title, p.name)
+		if !isReasonableLyrics(r.Lyrics) {
+			log.Printf("[lyrics] %s text too long, skipping: %s — %s", p.name, artist, title)
+			return lookupResult{Status: lookupNotFound}
+		}
+	}
+	return r
+}
+
+// race starts the providers in order, hedging each one after s.hedgeDelay
+// (or immediately once the previous one has missed), and returns the most
+// confident answer. A zero delay runs them strictly one after another.
+func (s *Lyrics) race(ctx context.Context, artist, title string, duration float64) (providerResult, bool) {
+	providers := s.providers()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan providerResult, len(providers))
+	launched, pending := 0, 0
+
+	launch := func() {
+		p := providers[launched]
+		launched++
+		pending++
+		go func() {
+			results <- providerResult{p.name, p.run(ctx, artist, title, duration)}
+		}()
+	}
+
+	var hedge <-chan time.Time
+	armHedge := func() {
+		hedge = nil
+		if s.hedgeDelay > 0 && launched < len(providers) {
+			hedge = time.After(s.hedgeDelay)
+		}
+	}
+
+	var best providerResult
+	haveBest, failed := false, false
+
+	launch()
+	armHedge()
+
+	for pending > 0 {
+		select {
+		case <-hedge:
+			launch()
+			armHedge()
+			continue
+		case <-ctx.Done():
+			return providerResult{}, true
+		case r := <-results:
+			pending--
+
+			switch r.Status {
+			case lookupFound, lookupInstrumental:
+				if !haveBest || r.Confidence > best.Confidence {
+					best, haveBest = r, true
+				}
+			case lookupTransient:
+				log.Printf("[lyrics] %s failed: %s — %s: %v", r.name, artist, title, r.Err)
+				failed = true
+			case lookupNotFound:
+				if r.Err != nil {
+					log.Printf("[lyrics] %s rejected: %s — %s: %v", r.name, artist, title, r.Err)
+				}
+			}
+		}
+
+		if haveBest && (best.Confidence >= confidentMatch || pending == 0) {
+			return best, false
+		}
+		if pending == 0 && launched < len(providers) {
+			launch()
+			armHedge()
+		}
+	}
+
+	if haveBest {
+		return best, false
+	}
+	return providerResult{}, failed
+}
+
+func (s *Lyrics) providers() []*provider {
+	if s.geniusToken == "" {
+		return s.providerList[:1]
+	}
+	return s.providerList
+}
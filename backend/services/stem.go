@@ -0,0 +1,416 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"lastfm-lyrics/models"
+)
+
+// Stemmers follow the Snowball English (Porter2) and Russian algorithms.
+
+func stemWord(w string, langs ...string) string {
+	for _, lang := range langs {
+		switch {
+		case lang == "en" && isASCIIWord(w):
+			return stemEnglish(w)
+		case lang == "ru" && isCyrillicWord(w):
+			return stemRussian(w)
+		}
+	}
+	return w
+}
+
+// restoreDroppedG maps a Latin word like lovin or nothin to its -ing form
+// for stemming. Only done when that form also occurs in the lyrics and its
+// -ing is a real suffix, so quoted 'Berlin' or thin stay as they are.
+func restoreDroppedG(w string, vocab map[string]bool) string {
+	if len(w) < 4 || !strings.HasSuffix(w, "in") || !isASCIIWord(w) {
+		return w
+	}
+	ing := w + "g"
+	if !vocab[ing] || stemEnglish(ing) == ing {
+		return w
+	}
+	return ing
+}
+
+func isASCIIWord(w string) bool {
+	for i := 0; i < len(w); i++ {
+		if (w[i] < 'a' || w[i] > 'z') && w[i] != '\'' {
+			return false
+		}
+	}
+	return true
+}
+
+func isCyrillicWord(w string) bool {
+	for _, r := range w {
+		if (r < 'а' || r > 'я') && r != 'ё' {
+			return false
+		}
+	}
+	return true
+}
+
+var enExceptions = map[string]string{
+	"skis": "ski", "skies": "sky", "dying": "die", "lying": "lie", "tying": "tie",
+	"idly": "idl", "gently": "gentl", "ugly": "ugli", "early": "earli", "only": "onli",
+	"singly": "singl", "sky": "sky", "news": "news", "howe": "howe",
+	"atlas": "atlas", "cosmos": "cosmos", "bias": "bias", "andes": "andes",
+}
+
+var enStep1aInvariant = map[string]bool{
+	"inning": true, "outing": true, "canning": true, "herring": true,
+	"earring": true, "proceed": true, "exceed": true, "succeed": true,
+}
+
+type suffixRule struct {
+	suffix, replace string
+}
+
+var enStep2 = []suffixRule{
+	{"ization", "ize"}, {"ational", "ate"}, {"fulness", "ful"}, {"ousness", "ous"},
+	{"iveness", "ive"}, {"tional", "tion"}, {"biliti", "ble"}, {"lessli", "less"},
+	{"entli", "ent"}, {"ation", "ate"}, {"alism", "al"}, {"aliti", "al"},
+	{"ousli", "ous"}, {"iviti", "ive"}, {"fulli", "ful"}, {"enci", "ence"},
+	{"anci", "ance"}, {"abli", "able"}, {"izer", "ize"}, {"ator", "ate"},
+	{"alli", "al"}, {"bli", "ble"}, {"ogi", "og"}, {"li", ""},
+}
+
+var enStep3 = []suffixRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"alize", "al"}, {"icate", "ic"},
+	{"iciti", "ic"}, {"ative", ""}, {"ical", "ic"}, {"ness", ""}, {"ful", ""},
+}
+
+var enStep4 = []string{
+	"ement", "ance", "ence", "able", "ible", "ment", "ant", "ent", "ism",
+	"ate", "iti", "ous", "ive", "ize", "ion", "al", "er", "ic",
+}
+
+func isVowelEn(c byte) bool {
+	return c == 'a' || c == 'e' || c == 'i' || c == 'o' || c == 'u' || c == 'y'
+}
+
+func stemEnglish(w string) string {
+	if len(w) <= 2 {
+		return w
+	}
+	if exc, ok := enExceptions[w]; ok {
+		return exc
+	}
+
+	b := []byte(w)
+	for i := range b {
+		if b[i] == 'y' && (i == 0 || isVowelEn(b[i-1])) {
+			b[i] = 'Y'
+		}
+	}
+
+	r1 := afterVowelRun(b, 0)
+	for _, p := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(w, p) {
+			r1 = len(p)
+		}
+	}
+	r2 := afterVowelRun(b, r1)
+
+	has := func(s string) bool { return strings.HasSuffix(string(b), s) }
+	hasVowel := func(s []byte) bool {
+		for _, c := range s {
+			if isVowelEn(c) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Step 0
+	for _, s := range []string{"'s'", "'s", "'"} {
+		if has(s) {
+			b = b[:len(b)-len(s)]
+			break
+		}
+	}
+
+	// Step 1a
+	switch {
+	case has("sses"):
+		b = b[:len(b)-2]
+	case has("ied"), has("ies"):
+		if len(b) > 4 {
+			b = append(b[:len(b)-3], 'i')
+		} else {
+			b = b[:len(b)-1]
+		}
+	case has("us"), has("ss"):
+	case has("s"):
+		if len(b) >= 2 && hasVowel(b[:len(b)-2]) {
+			b = b[:len(b)-1]
+		}
+	}
+	if enStep1aInvariant[string(b)] {
+		return string(b)
+	}
+
+	// Step 1b
+	for _, s := range []string{"eedly", "ingly", "edly", "eed", "ing", "ed"} {
+		if !has(s) {
+			continue
+		}
+		start := len(b) - len(s)
+		if s == "eed" || s == "eedly" {
+			if start >= r1 {
+				b = append(b[:start], "ee"...)
+			}
+			break
+		}
+		if !hasVowel(b[:start]) {
+			break
+		}
+		b = b[:start]
+		switch {
+		case has("at"), has("bl"), has("iz"):
+			b = append(b, 'e')
+		case endsDouble(b):
+			b = b[:len(b)-1]
+		case r1 >= len(b) && endsShortSyllable(b):
+			b = append(b, 'e')
+		}
+		break
+	}
+
+	// Step 1c
+	if n := len(b); n > 2 && (b[n-1] == 'y' || b[n-1] == 'Y') && !isVowelEn(b[n-2]) {
+		b[n-1] = 'i'
+	}
+
+	// Step 2
+	for _, rule := range enStep2 {
+		if !has(rule.suffix) {
+			continue
+		}
+		start := len(b) - len(rule.suffix)
+		ok := start >= r1
+		switch rule.suffix {
+		case "ogi":
+			ok = ok && start > 0 && b[start-1] == 'l'
+		case "li":
+			ok = ok && start > 0 && strings.IndexByte("cdeghkmnrt", b[start-1]) >= 0
+		}
+		if ok {
+			b = append(b[:start], rule.replace...)
+		}
+		break
+	}
+
+	// Step 3
+	for _, rule := range enStep3 {
+		if !has(rule.suffix) {
+			continue
+		}
+		start := len(b) - len(rule.suffix)
+		if start >= r1 && (rule.suffix != "ative" || start >= r2) {
+			b = append(b[:start], rule.replace...)
+		}
+		break
+	}
+
+	// Step 4
+	for _, s := range enStep4 {
+		if !has(s) {
+			continue
+		}
+		start := len(b) - len(s)
+		if start >= r2 && (s != "ion" || (start > 0 && (b[start-1] == 's' || b[start-1] == 't'))) {
+			b = b[:start]
+		}
+		break
+	}
+
+	// Step 5
+	if n := len(b); n > 0 {
+		start := n - 1
+		switch b[start] {
+		case 'e':
+			if start >= r2 || (start >= r1 && !endsShortSyllable(b[:start])) {
+				b = b[:start]
+			}
+		case 'l':
+			if start >= r2 && start > 0 && b[start-1] == 'l' {
+				b = b[:start]
+			}
+		}
+	}
+
+	return strings.ToLower(string(b))
+}
+
+func afterVowelRun(b []byte, start int) int {
+	for i := start + 1; i < len(b); i++ {
+		if !isVowelEn(b[i]) && isVowelEn(b[i-1]) {
+			return i + 1
+		}
+	}
+	return len(b)
+}
+
+func endsDouble(b []byte) bool {
+	n := len(b)
+	return n >= 2 && b[n-1] == b[n-2] && strings.IndexByte("bdfgmnprt", b[n-1]) >= 0
+}
+
+func endsShortSyllable(b []byte) bool {
+	n := len(b)
+	switch {
+	case n >= 3:
+		return !isVowelEn(b[n-3]) && isVowelEn(b[n-2]) && !isVowelEn(b[n-1]) &&
+			b[n-1] != 'w' && b[n-1] != 'x' && b[n-1] != 'Y'
+	case n == 2:
+		return isVowelEn(b[0]) && !isVowelEn(b[1])
+	}
+	return false
+}
+
+// Russian endings. Group 1 endings only match after а or я.
+var (
+	ruPerfectiveGerund1 = []string{"в", "вши", "вшись"}
+	ruPerfectiveGerund2 = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	ruAdjective         = []string{
+		"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+	}
+	ruParticiple1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	ruParticiple2 = []string{"ивш", "ывш", "ующ"}
+	ruReflexive   = []string{"ся", "сь"}
+	ruVerb1       = []string{
+		"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно",
+	}
+	ruVerb2 = []string{
+		"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым",
+		"ен", "ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю",
+	}
+	ruNoun = []string{
+		"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой",
+		"ий", "й", "иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь",
+		"ию", "ью", "ю", "ия", "ья", "я",
+	}
+	ruDerivational = []string{"ост", "ость"}
+	ruSuperlative  = []string{"ейш", "ейше"}
+)
+
+func isVowelRu(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+func stemRussian(w string) string {
+	word := []rune(strings.ReplaceAll(w, "ё", "е"))
+
+	rv := len(word)
+	for i, r := range word {
+		if isVowelRu(r) {
+			rv = i + 1
+			break
+		}
+	}
+	if rv == len(word) {
+		return string(word)
+	}
+
+	r1 := len(word)
+	for i := 1; i < len(word); i++ {
+		if !isVowelRu(word[i]) && isVowelRu(word[i-1]) {
+			r1 = i + 1
+			break
+		}
+	}
+	r2 := len(word)
+	for i := r1 + 1; i < len(word); i++ {
+		if !isVowelRu(word[i]) && isVowelRu(word[i-1]) {
+			r2 = i + 1
+			break
+		}
+	}
+
+	// Step 1
+	if s, ok := ruRemove(word, rv, ruPerfectiveGerund1, ruPerfectiveGerund2); ok {
+		word = s
+	} else {
+		if s, ok := ruRemove(word, rv, nil, ruReflexive); ok {
+			word = s
+		}
+		if s, ok := ruRemove(word, rv, nil, ruAdjective); ok {
+			word = s
+			if s, ok := ruRemove(word, rv, ruParticiple1, ruParticiple2); ok {
+				word = s
+			}
+		} else if s, ok := ruRemove(word, rv, ruVerb1, ruVerb2); ok {
+			word = s
+		} else if s, ok := ruRemove(word, rv, nil, ruNoun); ok {
+			word = s
+		}
+	}
+
+	// Step 2
+	if n := len(word); n-1 >= rv && word[n-1] == 'и' {
+		word = word[:n-1]
+	}
+
+	// Step 3
+	if s, ok := ruRemove(word, r2, nil, ruDerivational); ok {
+		word = s
+	}
+
+	// Step 4
+	if s, ok := ruRemove(word, rv, nil, ruSuperlative); ok {
+		word = s
+		if n := len(word); n >= 2 && n-2 >= rv && word[n-1] == 'н' && word[n-2] == 'н' {
+			word = word[:n-1]
+		}
+	} else if n := len(word); n >= 2 && n-2 >= rv && word[n-1] == 'н' && word[n-2] == 'н' {
+		word = word[:n-1]
+	} else if n > 0 && n-1 >= rv && word[n-1] == 'ь' {
+		word = word[:n-1]
+	}
+
+	return string(word)
+}
+
+// ruRemove removes the longest ending from either group that lies in the
+// region starting at from.
+func ruRemove(word []rune, from int, group1, group2 []string) ([]rune, bool) {
+	best, bestLen, inGroup1 := "", 0, false
+	check := func(endings []string, g1 bool) {
+		for _, e := range endings {
+			n := len([]rune(e))
+			if n > bestLen && n <= len(word)-from && strings.HasSuffix(string(word), e) {
+				best, bestLen, inGroup1 = e, n, g1
+			}
+		}
+	}
+	check(group1, true)
+	check(group2, false)
+
+	if best == "" {
+		return word, false
+	}
+	start := len(word) - bestLen
+	if inGroup1 && (start-1 < from || (word[start-1] != 'а' && word[start-1] != 'я')) {
+		return word, false
+	}
+	return word[:start], true
+}
+
+func mostFrequentForm(forms map[string]int) (string, []models.WordForm) {
+	list := make([]models.WordForm, 0, len(forms))
+	for w, c := range forms {
+		list = append(list, models.WordForm{Word: w, Count: c})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Word < list[j].Word
+	})
+	return list[0].Word, list
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lastfm-lyrics/models"
+)
+
+// The testdata/stem files hold "word stem" pairs sampled from the Snowball
+// project's voc.txt and output.txt for each language; the last few lines of
+// russian.txt are common lyric words.
+func TestStemSnowballSamples(t *testing.T) {
+	stemmers := map[string]func(string) string{
+		"english": stemEnglish,
+		"russian": stemRussian,
+	}
+
+	for lang, stem := range stemmers {
+		t.Run(lang, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "stem", lang+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			sc := bufio.NewScanner(f)
+			for line := 1; sc.Scan(); line++ {
+				fields := strings.Fields(sc.Text())
+				if len(fields) != 2 {
+					t.Fatalf("%s.txt:%d: want \"word stem\", got %q", lang, line, sc.Text())
+				}
+				if got := stem(fields[0]); got != fields[1] {
+					t.Errorf("%s(%q) = %q, want %q", lang, fields[0], got, fields[1])
+				}
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestStemWord(t *testing.T) {
+	tests := []struct {
+		word  string
+		langs []string
+		want  string
+	}{
+		{"loving", []string{"en"}, "love"},
+		{"песнями", []string{"ru"}, "песн"},
+		{"песнями", []string{"en"}, "песнями"},
+		{"loving", []string{"ru"}, "loving"},
+		{"loving", []string{"", "ru", "en"}, "love"},
+		{"liebe", []string{"de"}, "liebe"},
+	}
+	for _, tt := range tests {
+		if got := stemWord(tt.word, tt.langs...); got != tt.want {
+			t.Errorf("stemWord(%q, %v) = %q, want %q", tt.word, tt.langs, got, tt.want)
+		}
+	}
+}
+
+func TestRestoreDroppedG(t *testing.T) {
+	vocab := map[string]bool{"loving": true, "nothing": true, "thing": true, "doing": true}
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"lovin", "loving"},
+		{"nothin", "nothing"},
+		{"doin", "doing"},
+		{"thin", "thin"},
+		{"berlin", "berlin"},
+		{"again", "again"},
+		{"within", "within"},
+		{"rain", "rain"},
+		{"cryin", "cryin"},
+	}
+	for _, tt := range tests {
+		if got := restoreDroppedG(tt.word, vocab); got != tt.want {
+			t.Errorf("restoreDroppedG(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeWordsDroppedG(t *testing.T) {
+	a := NewAnalyzer(t.TempDir(), nil)
+	lyrics := map[string]string{
+		"a": "lovin' you, lovin' you\nwe keep loving 'Berlin'",
+		"b": "loving the rain'",
+	}
+
+	words := func(stem bool) map[string]int {
+		out := make(map[string]int)
+		for _, wc := range a.AnalyzeWords(lyrics, models.AnalysisFilters{Stem: stem, Lang: "en"}).Words {
+			if stem {
+				out[wc.Stem] = wc.Count
+			} else {
+				out[wc.Word] = wc.Count
+			}
+		}
+		return out
+	}
+
+	raw := words(false)
+	if raw["lovin"] != 2 || raw["loving"] != 2 || raw["berlin"] != 1 || raw["rain"] != 1 {
+		t.Errorf("raw counts changed: %v", raw)
+	}
+	stemmed := words(true)
+	if stemmed["love"] != 4 || stemmed["berlin"] != 1 || stemmed["rain"] != 1 {
+		t.Errorf("stemmed counts: %v", stemmed)
+	}
+}
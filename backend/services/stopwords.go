@@ -0,0 +1,235 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const customStopWords = "custom"
+
+// stopSets is never modified after it is published, so a task can keep
+// using the snapshot it started with while the lists are reloaded.
+type stopSets map[string]map[string]bool
+
+func (s stopSets) detect(words []string) string {
+	best, bestHits := "", 0
+	for lang, set := range s {
+		if lang == customStopWords {
+			continue
+		}
+		hits := 0
+		for _, w := range words {
+			if set[w] {
+				hits++
+			}
+		}
+		if hits > bestHits || (hits == bestHits && hits > 0 && lang < best) {
+			best, bestHits = lang, hits
+		}
+	}
+	return best
+}
+
+func (s stopSets) contains(w string, langs ...string) bool {
+	if s[customStopWords][w] {
+		return true
+	}
+	for _, lang := range langs {
+		if s[lang][w] {
+			return true
+		}
+	}
+	return false
+}
+
+type Analyzer struct {
+	dir          string
+	lyricsSource func() ([]string, error)
+
+	refMu       sync.Mutex
+	ref         *referenceCorpus
+	refBuilding bool
+	refNext     time.Time
+	refRetry    time.Duration
+
+	editMu sync.Mutex
+	mu     sync.RWMutex
+	sets   stopSets
+	custom []string
+	stamp  string
+}
+
+func NewAnalyzer(dir string, lyricsSource func() ([]string, error)) *Analyzer {
+	a := &Analyzer{dir: dir, lyricsSource: lyricsSource}
+	a.Reload()
+	a.reference()
+	return a
+}
+
+func (a *Analyzer) Reload() {
+	a.editMu.Lock()
+	defer a.editMu.Unlock()
+
+	stamp := a.filesStamp()
+	sets := make(stopSets)
+	var custom []string
+
+	paths, _ := filepath.Glob(filepath.Join(a.dir, "stopwords-*.json"))
+	for _, path := range paths {
+		lang := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "stopwords-"), ".json")
+		words, err := readWordList(path)
+		if err != nil {
+			log.Printf("[analyzer] could not load %s: %v", path, err)
+			continue
+		}
+		if lang == customStopWords {
+			custom = words
+		}
+		sets[lang] = wordSet(words)
+		log.Printf("[analyzer] loaded %d %s words from %s", len(words), lang, path)
+	}
+
+	a.mu.Lock()
+	a.sets, a.custom, a.stamp = sets, custom, stamp
+	a.mu.Unlock()
+
+	log.Printf("[analyzer] stop word sets: %d", len(sets))
+}
+
+func (a *Analyzer) Watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		a.mu.RLock()
+		stamp := a.stamp
+		a.mu.RUnlock()
+
+		if a.filesStamp() != stamp {
+			log.Printf("[analyzer] stop word files changed, reloading")
+			a.Reload()
+		}
+	}
+}
+
+func (a *Analyzer) filesStamp() string {
+	paths, _ := filepath.Glob(filepath.Join(a.dir, "stopwords-*.json"))
+	var sb strings.Builder
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&sb, "%s:%d:%d;", path, fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	return sb.String()
+}
+
+func (a *Analyzer) snapshot() stopSets {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.sets
+}
+
+func (a *Analyzer) Languages() map[string]int {
+	sets := a.snapshot()
+	langs := make(map[string]int, len(sets))
+	for lang, set := range sets {
+		langs[lang] = len(set)
+	}
+	return langs
+}
+
+func (a *Analyzer) CustomStopWords() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]string{}, a.custom...)
+}
+
+func (a *Analyzer) AddStopWords(words []string) ([]string, error) {
+	return a.editCustom(func(custom []string, set map[string]bool) []string {
+		for _, w := range NormalizeWordList(words) {
+			if !set[w] {
+				custom = append(custom, w)
+			}
+		}
+		return custom
+	})
+}
+
+func (a *Analyzer) RemoveStopWords(words []string) ([]string, error) {
+	drop := wordSet(NormalizeWordList(words))
+	return a.editCustom(func(custom []string, _ map[string]bool) []string {
+		var kept []string
+		for _, w := range custom {
+			if !drop[w] {
+				kept = append(kept, w)
+			}
+		}
+		return kept
+	})
+}
+
+func (a *Analyzer) editCustom(edit func(custom []string, set map[string]bool) []string) ([]string, error) {
+	a.editMu.Lock()
+	defer a.editMu.Unlock()
+
+	a.mu.RLock()
+	current, sets := a.custom, a.sets
+	a.mu.RUnlock()
+
+	custom := edit(append([]string{}, current...), sets[customStopWords])
+	if err := writeWordList(filepath.Join(a.dir, "stopwords-"+customStopWords+".json"), custom); err != nil {
+		return nil, err
+	}
+
+	next := make(stopSets, len(sets))
+	for lang, set := range sets {
+		next[lang] = set
+	}
+	next[customStopWords] = wordSet(custom)
+
+	a.mu.Lock()
+	a.sets, a.custom, a.stamp = next, custom, a.filesStamp()
+	a.mu.Unlock()
+
+	return append([]string{}, custom...), nil
+}
+
+func readWordList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	words := make([]string, 0, len(raw))
+	for _, w := range raw {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return words, nil
+}
+
+func writeWordList(path string, words []string) error {
+	if words == nil {
+		words = []string{}
+	}
+	data, err := json.MarshalIndent(words, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+var errNoTags = errors.New("no supported tags")
+
+// maxTagSize bounds tag blocks read into memory; sizes come from the file
+// itself and a damaged header can claim hundreds of megabytes.
+const maxTagSize = 64 << 20
+
+// maxOggPacket bounds the Ogg comment packet; it is assembled from pages until
+// a segment ends it, so a stream that never does would otherwise grow forever.
+const maxOggPacket = 16 << 20
+
+type audioTags struct {
+	Artist string
+	Title  string
+	Lyrics string
+}
+
+func readTags(path string) (*audioTags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		return readID3(f)
+	case ".flac":
+		return readFLAC(f)
+	case ".ogg", ".oga", ".opus":
+		return readOgg(f)
+	case ".m4a", ".mp4", ".aac", ".alac":
+		return readMP4(f)
+	}
+	return nil, errNoTags
+}
+
+func readID3(r io.Reader) (*audioTags, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:3]) != "ID3" {
+		return nil, errNoTags
+	}
+
+	version := header[3]
+	flags := header[5]
+	size := syncsafe(header[6:10])
+
+	if size > maxTagSize {
+		return nil, errNoTags
+	}
+	// ReadAll grows with the data actually present, so a truncated file
+	// never costs the full claimed size.
+	data, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < size {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if version < 4 && flags&0x80 != 0 {
+		data = unsync(data)
+	}
+
+	if flags&0x40 != 0 && len(data) >= 4 {
+		var ext int
+		if version == 4 {
+			ext = syncsafe(data[:4])
+		} else {
+			ext = int(binary.BigEndian.Uint32(data[:4])) + 4
+		}
+		if ext > len(data) {
+			return nil, errNoTags
+		}
+		data = data[ext:]
+	}
+
+	tags := &audioTags{}
+	var synced string
+
+	idLen, headLen := 4, 10
+	if version == 2 {
+		idLen, headLen = 3, 6
+	}
+
+	for len(data) >= headLen {
+		id := string(data[:idLen])
+		if id[0] == 0 {
+			break
+		}
+
+		var frameSize int
+		var formatFlags byte
+		switch version {
+		case 2:
+			frameSize = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(data[4:8]))
+		default:
+			frameSize = syncsafe(data[4:8])
+			formatFlags = data[9]
+		}
+
+		if frameSize <= 0 || headLen+frameSize > len(data) {
+			break
+		}
+		frame := data[headLen : headLen+frameSize]
+		data = data[headLen+frameSize:]
+
+		if formatFlags&0x02 != 0 {
+			frame = unsync(frame)
+		}
+		if formatFlags&0x01 != 0 && len(frame) >= 4 {
+			frame = frame[4:]
+		}
+		if len(frame) == 0 {
+			continue
+		}
+
+		switch id {
+		case "TPE1", "TP1":
+			tags.Artist = firstValue(decodeID3Text(frame[0], frame[1:]))
+		case "TIT2", "TT2":
+			tags.Title = firstValue(decodeID3Text(frame[0], frame[1:]))
+		case "USLT", "ULT":
+			if tags.Lyrics == "" {
+				tags.Lyrics = parseUSLT(frame)
+			}
+		case "SYLT", "SLT":
+			if synced == "" {
+				synced = parseSYLT(frame)
+			}
+		}
+	}
+
+	if tags.Lyrics == "" {
+		tags.Lyrics = synced
+	}
+	return tags, nil
+}
+
+func parseUSLT(frame []byte) string {
+	if len(frame) < 4 {
+		return ""
+	}
+	enc := frame[0]
+	_, rest := splitID3String(enc, frame[4:])
+	return strings.TrimSpace(decodeID3Text(enc, rest))
+}
+
+func parseSYLT(frame []byte) string {
+	if len(frame) < 6 {
+		return ""
+	}
+	enc := frame[0]
+	_, rest := splitID3String(enc, frame[6:])
+
+	var parts []string
+	multiline := false
+	for len(rest) > 0 {
+		var text []byte
+		text, rest = splitID3String(enc, rest)
+		if len(rest) < 4 {
+			rest = nil
+		} else {
+			rest = rest[4:]
+		}
+		s := decodeID3Text(enc, text)
+		if strings.HasPrefix(s, "\n") || strings.HasPrefix(s, "\r") {
+			multiline = true
+		}
+		parts = append(parts, s)
+	}
+
+	if multiline {
+		return strings.TrimSpace(strings.Join(parts, ""))
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+func splitID3String(enc byte, b []byte) ([]byte, []byte) {
+	if enc == 1 || enc == 2 {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return b[:i], b[i+2:]
+			}
+		}
+		return b, nil
+	}
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return b[:i], b[i+1:]
+	}
+	return b, nil
+}
+
+func decodeID3Text(enc byte, b []byte) string {
+	switch enc {
+	case 0:
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return strings.TrimRight(string(runes), "\x00")
+	case 1, 2:
+		bigEndian := enc == 2
+		if len(b) >= 2 {
+			if b[0] == 0xFE && b[1] == 0xFF {
+				bigEndian, b = true, b[2:]
+			} else if b[0] == 0xFF && b[1] == 0xFE {
+				bigEndian, b = false, b[2:]
+			}
+		}
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			if bigEndian {
+				u[i] = binary.BigEndian.Uint16(b[2*i:])
+			} else {
+				u[i] = binary.LittleEndian.Uint16(b[2*i:])
+			}
+		}
+		return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+	default:
+		return strings.TrimRight(string(b), "\x00")
+	}
+}
+
+func firstValue(s string) string {
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+func unsync(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		out = append(out, b[i])
+		if b[i] == 0xFF && i+1 < len(b) && b[i+1] == 0x00 {
+			i++
+		}
+	}
+	return out
+}
+
+func readFLAC(r io.ReadSeeker) (*audioTags, error) {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != "fLaC" {
+		return nil, errNoTags
+	}
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7f
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		if blockType == 4 {
+			block := make([]byte, length)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return nil, err
+			}
+			return parseVorbisComments(block)
+		}
+
+		if last {
+			return nil, errNoTags
+		}
+		if _, err := r.Seek(int64(length), io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readOgg(r io.Reader) (*audioTags, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 27)
+
+	var packet []byte
+	packets := 0
+	var serial uint32
+
+	for packets < 2 {
+		if _, err := io.ReadFull(br, header); err != nil {
+			return nil, err
+		}
+		if string(header[:4]) != "OggS" {
+			return nil, errNoTags
+		}
+
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(br, segments); err != nil {
+			return nil, err
+		}
+
+		total := 0
+		for _, s := range segments {
+			total += int(s)
+		}
+		body := make([]byte, total)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return nil, err
+		}
+
+		if packets == 0 && len(packet) == 0 {
+			serial = pageSerial
+		}
+		if pageSerial != serial {
+			continue
+		}
+
+		offset := 0
+		for _, s := range segments {
+			packet = append(packet, body[offset:offset+int(s)]...)
+			offset += int(s)
+			if len(packet) > maxOggPacket {
+				return nil, fmt.Errorf("ogg: comment packet exceeds %d bytes", maxOggPacket)
+			}
+			if s < 255 {
+				packets++
+				if packets == 2 {
+					break
+				}
+				packet = packet[:0]
+			}
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+		return parseVorbisComments(packet[7:])
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		return parseVorbisComments(packet[8:])
+	}
+	return nil, errNoTags
+}
+
+func parseVorbisComments(b []byte) (*audioTags, error) {
+	next := func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := int(binary.LittleEndian.Uint32(b))
+		if n < 0 || 4+n > len(b) {
+			return nil, false
+		}
+		v := b[4 : 4+n]
+		b = b[4+n:]
+		return v, true
+	}
+
+	if _, ok := next(); !ok {
+		return nil, fmt.Errorf("vorbis comment: bad vendor string")
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("vorbis comment: truncated")
+	}
+	count := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+
+	tags := &audioTags{}
+	var unsynced string
+	for i := 0; i < count; i++ {
+		c, ok := next()
+		if !ok {
+			break
+		}
+		key, value, found := strings.Cut(string(c), "=")
+		if !found {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "ARTIST":
+			if tags.Artist == "" {
+				tags.Artist = strings.TrimSpace(value)
+			}
+		case "TITLE":
+			if tags.Title == "" {
+				tags.Title = strings.TrimSpace(value)
+			}
+		case "LYRICS":
+			if tags.Lyrics == "" {
+				tags.Lyrics = strings.TrimSpace(value)
+			}
+		case "UNSYNCEDLYRICS":
+			unsynced = strings.TrimSpace(value)
+		}
+	}
+
+	if tags.Lyrics == "" {
+		tags.Lyrics = unsynced
+	}
+	return tags, nil
+}
+
+func readMP4(r io.ReadSeeker) (*audioTags, error) {
+	for {
+		size, kind, headLen, err := readAtomHeader(r)
+		if err != nil {
+			return nil, errNoTags
+		}
+		if kind == "moov" {
+			if size == 0 || size > maxTagSize {
+				return nil, errNoTags
+			}
+			moov := make([]byte, size-headLen)
+			if _, err := io.ReadFull(r, moov); err != nil {
+				return nil, err
+			}
+			return parseMoov(moov)
+		}
+		if size == 0 {
+			return nil, errNoTags
+		}
+		if _, err := r.Seek(size-headLen, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readAtomHeader(r io.Reader) (size int64, kind string, headLen int64, err error) {
+	h := make([]byte, 8)
+	if _, err = io.ReadFull(r, h); err != nil {
+		return
+	}
+	size = int64(binary.BigEndian.Uint32(h))
+	kind = string(h[4:8])
+	headLen = 8
+	if size == 1 {
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(r, ext); err != nil {
+			return
+		}
+		size = int64(binary.BigEndian.Uint64(ext))
+		headLen = 16
+	}
+	if size != 0 && size < headLen {
+		err = errNoTags
+	}
+	return
+}
+
+func parseMoov(moov []byte) (*audioTags, error) {
+	udta := findAtom(moov, "udta")
+	meta := findAtom(udta, "meta")
+	if len(meta) < 4 {
+		return nil, errNoTags
+	}
+	ilst := findAtom(meta[4:], "ilst")
+	if ilst == nil {
+		return nil, errNoTags
+	}
+
+	return &audioTags{
+		Artist: mp4Text(findAtom(ilst, "\xa9ART")),
+		Title:  mp4Text(findAtom(ilst, "\xa9nam")),
+		Lyrics: strings.TrimSpace(mp4Text(findAtom(ilst, "\xa9lyr"))),
+	}, nil
+}
+
+func findAtom(b []byte, kind string) []byte {
+	for len(b) >= 8 {
+		size := int(binary.BigEndian.Uint32(b))
+		headLen := 8
+		if size == 1 && len(b) >= 16 {
+			size = int(binary.BigEndian.Uint64(b[8:16]))
+			headLen = 16
+		} else if size == 0 {
+			size = len(b)
+		}
+		if size < headLen || size > len(b) {
+			return nil
+		}
+		if string(b[4:8]) == kind {
+			return b[headLen:size]
+		}
+		b = b[size:]
+	}
+	return nil
+}
+
+func mp4Text(item []byte) string {
+	data := findAtom(item, "data")
+	if len(data) < 8 {
+		return ""
+	}
+	return strings.TrimSpace(string(data[8:]))
+}
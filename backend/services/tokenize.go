@@ -0,0 +1,94 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’' || r == 'ʼ' || r == '‘'
+}
+
+func isHyphen(r rune) bool {
+	return r == '-' || r == '‐' || r == '‑'
+}
+
+// Han text has no spaces between words, so each ideograph is a token of
+// its own. Kana and other scripts are split on non-letters.
+func isIdeograph(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
+func tokenize(text string) []string {
+	runes := []rune(strings.ToLower(text))
+
+	var tokens []string
+	var buf []rune
+	hasLetter := false
+
+	flush := func() {
+		if hasLetter {
+			tokens = append(tokens, string(buf))
+		}
+		buf, hasLetter = buf[:0], false
+	}
+
+	for i, r := range runes {
+		switch {
+		case isIdeograph(r):
+			flush()
+			tokens = append(tokens, string(r))
+
+		case unicode.IsLetter(r):
+			buf = append(buf, r)
+			hasLetter = true
+
+		case unicode.IsMark(r) || unicode.IsDigit(r):
+			if unicode.IsMark(r) && len(buf) == 0 {
+				continue
+			}
+			buf = append(buf, r)
+
+		case isApostrophe(r) || isHyphen(r):
+			joins := len(buf) > 0 && i+1 < len(runes) && isWordRune(runes[i+1]) && !isIdeograph(runes[i+1])
+			if isApostrophe(r) && joins {
+				joins = unicode.IsLetter(runes[i+1])
+			}
+			if !joins {
+				flush()
+				continue
+			}
+			if isApostrophe(r) {
+				buf = append(buf, '\'')
+			} else {
+				buf = append(buf, '-')
+			}
+
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// QueryWord normalizes a user-supplied word the way lyrics are tokenized,
+// so "Lovin'" looks up "loving". Input that is not a single word is only
+// lowercased, and AnalyzeWords reports it.
+func QueryWord(s string) string {
+	if tokens := tokenize(s); len(tokens) == 1 {
+		return tokens[0]
+	}
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func countable(w string) bool {
+	r, size := utf8.DecodeRuneInString(w)
+	return size < len(w) || isIdeograph(r)
+}
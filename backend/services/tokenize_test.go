@@ -0,0 +1,60 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		lang string
+		in   string
+		want []string
+	}{
+		{"en", "Don't stop me now", []string{"don't", "stop", "me", "now"}},
+		{"en", "I’m every minute ’round", []string{"i'm", "every", "minute", "round"}},
+		{"en", "Rock-n-roll ain't noise pollution", []string{"rock-n-roll", "ain't", "noise", "pollution"}},
+		{"en", "2Pac in '96, back in 1999", []string{"2pac", "in", "back", "in"}},
+		{"en", "Dancing -- 'til the end -", []string{"dancing", "til", "the", "end"}},
+		{"en", "The boys' car", []string{"the", "boys", "car"}},
+		{"en", "Lovin' you, nothin' else", []string{"lovin", "you", "nothin", "else"}},
+		{"en", "'Berlin' 'again' within' rain'", []string{"berlin", "again", "within", "rain"}},
+		{"de", "Über den Wolken muß die Freiheit grenzenlos sein", []string{"über", "den", "wolken", "muß", "die", "freiheit", "grenzenlos", "sein"}},
+		{"de", "Gib's mir, Kaffee-Klatsch", []string{"gib's", "mir", "kaffee-klatsch"}},
+		{"fr", "Je t'aime, l'amour aujourd'hui", []string{"je", "t'aime", "l'amour", "aujourd'hui"}},
+		{"fr", "C’est peut-être là-bas", []string{"c'est", "peut-être", "là-bas"}},
+		{"es", "¿Dónde estás, corazón? ¡Ay, niño!", []string{"dónde", "estás", "corazón", "ay", "niño"}},
+		{"uk", "Ще не вмерла Україна, п’ять м'яких", []string{"ще", "не", "вмерла", "україна", "п'ять", "м'яких"}},
+		{"ru", "Ёлки-палки, всё хорошо, 5-й день", []string{"ёлки-палки", "всё", "хорошо", "5-й", "день"}},
+		{"ja", "愛してるよ、東京タワー", []string{"愛", "してるよ", "東", "京", "タワー"}},
+		{"ko", "사랑해요, 서울의 밤", []string{"사랑해요", "서울의", "밤"}},
+		{"ar", "حَبيبي يا نور العين", []string{"حَبيبي", "يا", "نور", "العين"}},
+		{"mixed", "Baby, ты моя love-story", []string{"baby", "ты", "моя", "love-story"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang+"/"+tt.in, func(t *testing.T) {
+			if got := tokenize(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountable(t *testing.T) {
+	tests := []struct {
+		word string
+		want bool
+	}{
+		{"a", false},
+		{"я", false},
+		{"ok", true},
+		{"愛", true},
+		{"2pac", true},
+	}
+	for _, tt := range tests {
+		if got := countable(tt.word); got != tt.want {
+			t.Errorf("countable(%q) = %v, want %v", tt.word, got, tt.want)
+		}
+	}
+}
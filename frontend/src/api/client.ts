@@ -52,8 +52,9 @@ export async function startArtistAnalysis(
   return data.task_id;
 }
 
-export async function getStatus(taskId: string) {
-  const resp = await fetch(`${API_URL}/api/status/${taskId}`);
+export async function getStatus(taskId: string, topN?: number) {
+  const query = topN ? `?top_n=${topN}` : "";
+  const resp = await fetch(`${API_URL}/api/status/${taskId}${query}`);
 
   if (!resp.ok) {
     throw new Error("Failed to get status");
@@ -1,7 +1,65 @@
+export interface Track {
+  artist: string;
+  title: string;
+  play_count: number;
+  duration?: number;
+  instrumental?: boolean;
+}
+
 export interface WordCount {
   word: string;
   count: number;
   tracks: string[];
+  stem?: string;
+  forms?: WordForm[];
+}
+
+export interface PhraseCount {
+  phrase: string;
+  words: number;
+  count: number;
+  tracks: string[];
+}
+
+export interface Collocation {
+  words: [string, string];
+  count: number;
+  pmi: number;
+  t_score: number;
+  log_likelihood: number;
+}
+
+export interface DistinctiveWord {
+  word: string;
+  count: number;
+  reference_count: number;
+  score: number;
+}
+
+export interface LexicalStats {
+  tokens: number;
+  types: number;
+  ttr: number;
+  mtld: number;
+  hdd: number;
+  hapax_legomena: number;
+  dis_legomena: number;
+  mean_word_length: number;
+  words_per_song: number;
+  zipf_exponent: number;
+  heaps_beta: number;
+  heaps: HeapsPoint[];
+}
+
+export interface HeapsPoint {
+  songs: number;
+  tokens: number;
+  types: number;
+}
+
+export interface WordForm {
+  word: string;
+  count: number;
 }
 
 export interface TaskResult {
@@ -9,12 +67,36 @@ export interface TaskResult {
   unique_tracks: number;
   lyrics_found: number;
   lyrics_missing: number;
+  instrumental: number;
   total_unique_words: number;
   total_word_count: number;
   words: WordCount[];
+  phrases?: PhraseCount[];
+  collocations?: Collocation[];
+  word_collocations?: Collocation[];
+  collocation_note?: string;
+  distinctive?: DistinctiveWord[];
+  stats?: LexicalStats;
+  track_stats?: TrackStats[];
+  missing_tracks?: Track[];
+  duplicates?: LyricsDuplicate[];
+  suspicious_matches?: LyricsDuplicate[];
+  languages?: Record<string, number>;
+  filters: AnalysisFilters;
   lyrics?: Record<string, string>;
 }
 
+export interface TrackStats {
+  track: string;
+  source: string;
+  confidence?: number;
+  synced: boolean;
+  duration?: number;
+  words_per_minute?: number;
+  first_vocal?: number;
+  vocal_coverage?: number;
+}
+
 export interface TaskStatus {
   id: string;
   phase: string;
@@ -27,3 +109,30 @@ export interface TaskStatus {
   result?: TaskResult;
 }
 
+export interface LyricsDuplicate {
+  tracks: string[];
+  kept?: string;
+  dropped?: string[];
+  similarity: number;
+  exact: boolean;
+}
+
+export interface AnalysisFilters {
+  exclude_stop_words: boolean;
+  lang?: string;
+  extra_stop_words?: string[];
+  keep_words?: string[];
+  stem?: boolean;
+  min_phrase_tracks: number;
+  collocation_window: number;
+  collocation_word?: string;
+  distinctive: string;
+  top_n: number;
+}
+
+export interface WordPage {
+  total: number;
+  offset: number;
+  limit: number;
+  words: WordCount[];
+}